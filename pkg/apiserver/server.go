@@ -11,7 +11,10 @@ import (
 	"time"
 )
 
-const HealthRoute = "/health"
+const (
+	HealthRoute    = "/health"
+	ReadinessRoute = "/ready"
+)
 
 type Settings struct {
 	Port         string
@@ -26,17 +29,20 @@ type ApiServer struct {
 
 	logger       mon.Logger
 	server       *http.Server
+	health       kernel.HealthChecker
 	defineRouter Define
 }
 
 func New(definer Define) *ApiServer {
 	return &ApiServer{
+		health:       kernel.ProvideHealthRegistry(),
 		defineRouter: definer,
 	}
 }
 
 func NewOnlyHealthRoute() *ApiServer {
 	return &ApiServer{
+		health:       kernel.ProvideHealthRegistry(),
 		defineRouter: func(_ cfg.Config, _ mon.Logger, _ *Definitions) {},
 	}
 }
@@ -62,7 +68,11 @@ func (a *ApiServer) BootWithInterfaces(config cfg.Config, logger mon.Logger, rou
 	addProfilingEndpoints(router)
 
 	router.GET(HealthRoute, func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{})
+		writeHealthReport(c, a.health.Health())
+	})
+
+	router.GET(ReadinessRoute, func(c *gin.Context) {
+		writeHealthReport(c, a.health.Readiness())
 	})
 
 	a.logger = logger
@@ -108,3 +118,13 @@ func (a *ApiServer) waitForStop(ctx context.Context) {
 
 	a.logger.Info("leaving api")
 }
+
+func writeHealthReport(c *gin.Context, report kernel.HealthReport) {
+	status := http.StatusOK
+
+	if !report.Healthy {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, report)
+}
@@ -34,6 +34,13 @@ func TestHealthRoute(t *testing.T) {
 	assertRouteReturnsResponse(t, ginEngine, httpRecorder, apiserver.HealthRoute, http.StatusOK)
 }
 
+func TestReadinessRoute(t *testing.T) {
+	ginEngine := setup(t)
+	httpRecorder := httptest.NewRecorder()
+
+	assertRouteReturnsResponse(t, ginEngine, httpRecorder, apiserver.ReadinessRoute, http.StatusServiceUnavailable)
+}
+
 func TestBaseProfilingEndpoint(t *testing.T) {
 	ginEngine := setup(t)
 	httpRecorder := httptest.NewRecorder()
@@ -41,6 +41,7 @@ type BatchRunner struct {
 	client s3iface.S3API
 	read   chan *Object
 	write  chan *Object
+	delete chan *Object
 }
 
 func (r *BatchRunner) Boot(config cfg.Config, logger mon.Logger) error {
@@ -52,6 +53,7 @@ func (r *BatchRunner) Boot(config cfg.Config, logger mon.Logger) error {
 	r.metric = mon.NewMetricDaemonWriter(defaultMetrics...)
 	r.read = make(chan *Object, 100)
 	r.write = make(chan *Object, 100)
+	r.delete = make(chan *Object, 100)
 
 	return nil
 }
@@ -65,6 +67,10 @@ func (r *BatchRunner) Run(ctx context.Context) error {
 		go r.executeWrite()
 	}
 
+	for i := 0; i < 10; i++ {
+		go r.executeDelete()
+	}
+
 	<-ctx.Done()
 
 	return nil
@@ -125,6 +131,27 @@ func (r *BatchRunner) executeWrite() {
 	}
 }
 
+func (r *BatchRunner) executeDelete() {
+	for object := range r.delete {
+		key := strings.Join([]string{*object.prefix, *object.Key}, "/")
+
+		input := &s3.DeleteObjectInput{
+			Bucket: object.bucket,
+			Key:    aws.String(key),
+		}
+
+		_, err := r.client.DeleteObject(input)
+
+		if err != nil {
+			object.Error = err
+		} else {
+			object.Exists = false
+		}
+
+		object.wg.Done()
+	}
+}
+
 func getDefaultRunnerMetrics(appId cfg.AppId) []*mon.MetricDatum {
 	name := appId.String()
 
@@ -28,11 +28,14 @@ type Settings struct {
 	Prefix string
 }
 
+//go:generate mockery -name=Store
 type Store interface {
 	Read(batch Batch)
 	ReadOne(obj *Object) error
 	Write(batch Batch)
 	WriteOne(obj *Object) error
+	Delete(batch Batch)
+	DeleteOne(obj *Object) error
 }
 
 type s3Store struct {
@@ -132,3 +135,26 @@ func (s *s3Store) Write(batch Batch) {
 
 	wg.Wait()
 }
+
+func (s *s3Store) DeleteOne(obj *Object) error {
+	s.Delete(Batch{obj})
+
+	return obj.Error
+}
+
+func (s *s3Store) Delete(batch Batch) {
+	wg := &sync.WaitGroup{}
+	wg.Add(len(batch))
+
+	for i := 0; i < len(batch); i++ {
+		batch[i].bucket = s.bucket
+		batch[i].prefix = s.prefix
+		batch[i].wg = wg
+	}
+
+	for i := 0; i < len(batch); i++ {
+		s.runner.delete <- batch[i]
+	}
+
+	wg.Wait()
+}
@@ -47,6 +47,8 @@ func (n baseNotifier) Send(ctx context.Context, notificationType string, value M
 	msg.Attributes["modelId"] = modelId
 	msg.Body = string(body)
 
+	stream.SetSchema(msg, modelId, n.version)
+
 	err = n.output.WriteOne(ctx, msg)
 
 	if err != nil {
@@ -1,6 +1,9 @@
 package json
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+)
 
 func Marshal(v interface{}) ([]byte, error) {
 	return json.Marshal(v)
@@ -9,3 +12,11 @@ func Marshal(v interface{}) ([]byte, error) {
 func Unmarshal(data []byte, v interface{}) error {
 	return json.Unmarshal(data, v)
 }
+
+// UnmarshalUseNumber decodes numbers in interfaces as json.Number instead of float64, so integers keep their precision
+func UnmarshalUseNumber(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	return decoder.Decode(v)
+}
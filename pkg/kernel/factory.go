@@ -24,9 +24,12 @@ func NewWithInterfaces(config cfg.Config, logger mon.Logger) *kernel {
 
 		booted:  make(chan struct{}),
 		running: make(chan struct{}),
+		stopped: make(chan struct{}),
 
 		config: config,
 		logger: logger.WithChannel("kernel"),
+		metric: mon.NewMetricDaemonWriter(),
+		health: ProvideHealthRegistry(),
 
 		factories: make([]ModuleFactory, 0),
 	}
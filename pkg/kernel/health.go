@@ -0,0 +1,144 @@
+package kernel
+
+import (
+	"sync"
+)
+
+// HealthCheckedModule can be implemented by a module which is able to tell if it is still able to
+// do its work, e.g. if the connection to its input is still alive.
+type HealthCheckedModule interface {
+	CheckHealth() error
+}
+
+type ModuleHealth struct {
+	Type      string `json:"type"`
+	IsRunning bool   `json:"running"`
+	Healthy   bool   `json:"healthy"`
+	Error     string `json:"error,omitempty"`
+}
+
+type HealthReport struct {
+	Healthy bool                    `json:"healthy"`
+	Modules map[string]ModuleHealth `json:"modules"`
+}
+
+type HealthChecker interface {
+	Health() HealthReport
+	Readiness() HealthReport
+}
+
+type HealthRegistry struct {
+	lck       sync.Mutex
+	modules   map[string]*ModuleState
+	isRunning bool
+}
+
+var healthRegistryContainer = struct {
+	sync.Mutex
+	instance *HealthRegistry
+}{}
+
+func ProvideHealthRegistry() *HealthRegistry {
+	healthRegistryContainer.Lock()
+	defer healthRegistryContainer.Unlock()
+
+	if healthRegistryContainer.instance != nil {
+		return healthRegistryContainer.instance
+	}
+
+	healthRegistryContainer.instance = NewHealthRegistry()
+
+	return healthRegistryContainer.instance
+}
+
+func NewHealthRegistry() *HealthRegistry {
+	return &HealthRegistry{
+		modules: make(map[string]*ModuleState),
+	}
+}
+
+func (r *HealthRegistry) Register(name string, state *ModuleState) {
+	r.lck.Lock()
+	defer r.lck.Unlock()
+
+	r.modules[name] = state
+}
+
+func (r *HealthRegistry) SetRunning(isRunning bool) {
+	r.lck.Lock()
+	defer r.lck.Unlock()
+
+	r.isRunning = isRunning
+}
+
+// Health reports unhealthy as soon as a module failed or its health check returns an error
+func (r *HealthRegistry) Health() HealthReport {
+	modules, _ := r.snapshot()
+
+	return buildReport(modules, false)
+}
+
+// Readiness additionally requires the kernel and all of its foreground modules to be running
+func (r *HealthRegistry) Readiness() HealthReport {
+	modules, isRunning := r.snapshot()
+
+	report := buildReport(modules, true)
+	report.Healthy = report.Healthy && isRunning
+
+	return report
+}
+
+// snapshot copies the registered modules, so the health checks of the modules don't run while holding the lock
+func (r *HealthRegistry) snapshot() (map[string]*ModuleState, bool) {
+	r.lck.Lock()
+	defer r.lck.Unlock()
+
+	modules := make(map[string]*ModuleState, len(r.modules))
+
+	for name, ms := range r.modules {
+		modules[name] = ms
+	}
+
+	return modules, r.isRunning
+}
+
+func buildReport(modules map[string]*ModuleState, requireRunning bool) HealthReport {
+	report := HealthReport{
+		Healthy: true,
+		Modules: make(map[string]ModuleHealth, len(modules)),
+	}
+
+	for name, ms := range modules {
+		health := checkModuleHealth(ms)
+
+		if requireRunning && !health.IsRunning && isForegroundModule(ms.Module) {
+			health.Healthy = false
+		}
+
+		report.Healthy = report.Healthy && health.Healthy
+		report.Modules[name] = health
+	}
+
+	return report
+}
+
+func checkModuleHealth(ms *ModuleState) ModuleHealth {
+	isRunning, err := ms.snapshot()
+
+	health := ModuleHealth{
+		Type:      ms.Module.GetType(),
+		IsRunning: isRunning,
+		Healthy:   true,
+	}
+
+	if hm, ok := ms.Module.(HealthCheckedModule); ok && err == nil && isRunning {
+		err = hm.CheckHealth()
+	}
+
+	if err != nil {
+		health.Healthy = false
+		health.Error = err.Error()
+	}
+
+	return health
+}
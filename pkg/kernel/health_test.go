@@ -0,0 +1,83 @@
+package kernel_test
+
+import (
+	"errors"
+	"github.com/applike/gosoline/pkg/kernel"
+	kernelMocks "github.com/applike/gosoline/pkg/kernel/mocks"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type healthCheckedModule struct {
+	*kernelMocks.Module
+	err error
+}
+
+func (m healthCheckedModule) CheckHealth() error {
+	return m.err
+}
+
+func newModuleState(typ string, isRunning bool, err error) *kernel.ModuleState {
+	module := new(kernelMocks.Module)
+	module.On("GetType").Return(typ)
+
+	return &kernel.ModuleState{
+		Module:    module,
+		IsRunning: isRunning,
+		Err:       err,
+	}
+}
+
+func TestHealthRegistry_Healthy(t *testing.T) {
+	registry := kernel.NewHealthRegistry()
+	registry.Register("foreground", newModuleState(kernel.TypeForeground, true, nil))
+	registry.Register("background", newModuleState(kernel.TypeBackground, false, nil))
+	registry.SetRunning(true)
+
+	assert.True(t, registry.Health().Healthy)
+	assert.True(t, registry.Readiness().Healthy)
+}
+
+func TestHealthRegistry_ModuleFailed(t *testing.T) {
+	registry := kernel.NewHealthRegistry()
+	registry.Register("foreground", newModuleState(kernel.TypeForeground, false, errors.New("failed")))
+	registry.SetRunning(true)
+
+	health := registry.Health()
+
+	assert.False(t, health.Healthy)
+	assert.Equal(t, "failed", health.Modules["foreground"].Error)
+	assert.False(t, registry.Readiness().Healthy)
+}
+
+func TestHealthRegistry_HealthCheckFailed(t *testing.T) {
+	module := new(kernelMocks.Module)
+	module.On("GetType").Return(kernel.TypeEssential)
+
+	registry := kernel.NewHealthRegistry()
+	registry.Register("consumer", &kernel.ModuleState{
+		Module: healthCheckedModule{
+			Module: module,
+			err:    errors.New("input is dead"),
+		},
+		IsRunning: true,
+	})
+	registry.SetRunning(true)
+
+	health := registry.Health()
+
+	assert.False(t, health.Healthy)
+	assert.Equal(t, "input is dead", health.Modules["consumer"].Error)
+}
+
+func TestHealthRegistry_NotReady(t *testing.T) {
+	registry := kernel.NewHealthRegistry()
+	registry.Register("foreground", newModuleState(kernel.TypeForeground, false, nil))
+
+	assert.True(t, registry.Health().Healthy)
+	assert.False(t, registry.Readiness().Healthy)
+
+	registry.SetRunning(true)
+
+	assert.False(t, registry.Readiness().Healthy)
+}
@@ -10,26 +10,35 @@ import (
 	"os"
 	"os/signal"
 	"sort"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 )
 
+const defaultShutdownTimeout = 10 * time.Second
+
 type kernel struct {
-	cfn     coffin.Coffin
-	sig     chan os.Signal
-	booted  chan struct{}
-	running chan struct{}
-	lck     sync.Mutex
-	wg      sync.WaitGroup
+	sig      chan os.Signal
+	booted   chan struct{}
+	running  chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+	lck      sync.Mutex
+	wg       sync.WaitGroup
 
 	config cfg.Config
 	logger mon.Logger
+	metric mon.MetricWriter
+	health *HealthRegistry
 
 	factories   []ModuleFactory
 	modules     sync.Map
 	moduleCount int
+	stages      []*stage
 	isRunning   bool
+
+	shutdownTimeout time.Duration
 }
 
 func (k *kernel) Add(name string, module Module) {
@@ -39,6 +48,7 @@ func (k *kernel) Add(name string, module Module) {
 	}
 
 	k.modules.Store(name, state)
+	k.health.Register(name, state)
 	k.moduleCount++
 }
 
@@ -60,6 +70,8 @@ func (k *kernel) Run() {
 	defer k.logger.Info("leaving kernel")
 	k.debugConfig()
 
+	k.readSettings()
+
 	err := k.runFactories()
 	if err != nil {
 		k.logger.Error(err, "error building additional modules by factories")
@@ -79,75 +91,182 @@ func (k *kernel) Run() {
 	signal.Notify(k.sig, syscall.SIGTERM)
 	signal.Notify(k.sig, syscall.SIGINT)
 
-	bootCoffin := coffin.New()
-
-	k.modules.Range(func(name interface{}, moduleState interface{}) bool {
-		bootCoffin.Gof(func() error {
-			return k.bootModule(name.(string))
-		}, "panic during boot of module %s", name)
+	k.stages, err = buildStages(k.getDependencies())
 
-		return true
-	})
-
-	bootErr := bootCoffin.Wait()
-
-	if bootErr != nil {
-		k.logger.Error(bootErr, "error during the boot process of the kernel")
+	if err != nil {
+		k.logger.Error(err, "error during the boot process of the kernel")
 		return
 	}
 
+	for _, s := range k.stages {
+		if err := k.bootStage(s); err != nil {
+			k.logger.Error(err, "error during the boot process of the kernel")
+			return
+		}
+	}
+
 	k.logger.Info("all modules booted")
 	close(k.booted)
 
-	var ctx context.Context
-	k.cfn, ctx = coffin.WithContext(context.Background())
 	k.wg.Add(k.moduleCount)
 
-	k.modules.Range(func(name interface{}, moduleState interface{}) bool {
-		k.cfn.Gof(func() error {
-			err := k.runModule(name.(string), ctx)
-			k.checkRunningForegroundModules()
-
-			return err
-		}, "panic during running of module %s", name)
-
-		return true
-	})
+	for _, s := range k.stages {
+		k.runStage(s)
+	}
 
 	k.checkRunningEssentialModules()
 	k.isRunning = true
+	k.health.SetRunning(true)
 	k.logger.Info("kernel up and running")
 	close(k.running)
 
+	// foreground modules could have already finished before the kernel was marked as running
+	k.checkRunningForegroundModules()
+
 	select {
-	case <-ctx.Done():
-		k.Stop("context done")
+	case <-k.stopped:
 	case sig := <-k.sig:
 		reason := fmt.Sprintf("signal %s", sig.String())
 		k.Stop(reason)
 	}
 
-	go func() {
-		timer := time.NewTimer(10 * time.Second)
-		<-timer.C
+	k.stopStages()
+}
+
+func (k *kernel) Stop(reason string) {
+	k.stopOnce.Do(func() {
+		k.isRunning = false
+		k.health.SetRunning(false)
+		k.logger.Infof("stopping kernel due to: %s", reason)
+		close(k.stopped)
+	})
+}
+
+func (k *kernel) bootStage(s *stage) error {
+	bootCoffin := coffin.New()
+
+	for _, name := range s.modules {
+		name := name
+
+		bootCoffin.Gof(func() error {
+			return k.bootModule(name)
+		}, "panic during boot of module %s", name)
+	}
+
+	return bootCoffin.Wait()
+}
+
+func (k *kernel) runStage(s *stage) {
+	k.logger.Infof("running modules of stage %d", s.index)
+
+	s.wg.Add(len(s.modules))
+
+	for _, name := range s.modules {
+		name := name
+
+		s.cfn.Gof(func() error {
+			err := k.runModule(name, s)
+			k.checkRunningForegroundModules()
+
+			return err
+		}, "panic during running of module %s", name)
+	}
 
-		err := errors.New("kernel was not able to shutdown in 10 seconds")
-		k.logger.Error(err, "kernel shutdown seems to be blocking.. exiting...")
+	go func() {
+		<-s.cfn.Dying()
 
-		os.Exit(1)
+		if s.cfn.Err() != nil {
+			k.Stop(fmt.Sprintf("a module of stage %d has failed", s.index))
+		}
 	}()
 
-	err = k.cfn.Wait()
+	s.wg.Wait()
+}
 
-	if err != nil {
+// stopStages stops the stages in reverse order, so every module is stopped before its dependencies
+func (k *kernel) stopStages() {
+	running := make([]string, 0)
+
+	for i := len(k.stages) - 1; i >= 0; i-- {
+		s := k.stages[i]
+
+		k.logger.Infof("stopping modules of stage %d", s.index)
+		killed := time.Now()
+		s.cfn.Kill(nil)
+
+		running = append(running, k.waitForStage(s, killed)...)
+	}
+
+	if len(running) == 0 {
+		return
+	}
+
+	err := fmt.Errorf("the modules [%s] were still running after their stop deadline", strings.Join(running, ", "))
+	k.logger.Error(err, "kernel was not able to shutdown all modules")
+}
+
+// waitForStage waits until all modules of the stage have stopped or until the stop deadlines of
+// all of them have passed. The deadlines are measured from the time the stage was killed, so every
+// stage gets the full stop timeout of its modules. It returns the names of the modules which are still running.
+func (k *kernel) waitForStage(s *stage, killed time.Time) []string {
+	modules := make([]string, len(s.modules))
+	copy(modules, s.modules)
+
+	sort.SliceStable(modules, func(i, j int) bool {
+		return k.getStopTimeout(modules[i]) < k.getStopTimeout(modules[j])
+	})
+
+	for _, name := range modules {
+		if !k.getModuleState(name).isRunning() {
+			continue
+		}
+
+		deadline := killed.Add(k.getStopTimeout(name))
+		timer := time.NewTimer(time.Until(deadline))
+
+		select {
+		case <-s.cfn.Dead():
+			timer.Stop()
+
+		case <-timer.C:
+		}
+	}
+
+	running := make([]string, 0)
+
+	for _, name := range s.modules {
+		if k.getModuleState(name).isRunning() {
+			running = append(running, name)
+		}
+	}
+
+	if len(running) > 0 {
+		return running
+	}
+
+	if err := s.cfn.Wait(); err != nil {
 		k.logger.Error(err, "error during the execution of the kernel")
 	}
+
+	return running
 }
 
-func (k *kernel) Stop(reason string) {
-	k.isRunning = false
-	k.logger.Infof("stopping kernel due to: %s", reason)
-	k.cfn.Kill(nil)
+func (k *kernel) getStopTimeout(name string) time.Duration {
+	ms := k.getModuleState(name)
+
+	if sm, ok := ms.Module.(StopTimeoutModule); ok {
+		return sm.GetStopTimeout()
+	}
+
+	return k.shutdownTimeout
+}
+
+func (k *kernel) readSettings() {
+	k.shutdownTimeout = defaultShutdownTimeout
+
+	if k.config.IsSet("kernel_shutdown_timeout") {
+		k.shutdownTimeout = k.config.GetDuration("kernel_shutdown_timeout") * time.Second
+	}
 }
 
 func (k *kernel) runFactories() error {
@@ -174,33 +293,93 @@ func (k *kernel) bootModule(name string) error {
 
 	logger := k.logger.WithChannel("default")
 	err := ms.Module.Boot(k.config, logger)
-	ms.Err = err
+	ms.setErr(err)
 
 	k.logger.Infof("booted module %s", name)
 
 	return err
 }
 
-func (k *kernel) runModule(name string, ctx context.Context) error {
+func (k *kernel) runModule(name string, s *stage) error {
 	defer k.logger.Info("stopped module " + name)
 
 	k.logger.Info("running module " + name)
 
 	ms := k.getModuleState(name)
-	ms.IsRunning = true
+	ms.setRunning(true)
 	k.wg.Done()
+	s.wg.Done()
 
 	defer func(ms *ModuleState) {
-		ms.IsRunning = false
+		ms.setRunning(false)
 	}(ms)
-	err := ms.Module.Run(ctx)
 
-	if err != nil {
-		k.logger.Error(err, "error running module "+name)
+	policy := getRestartPolicy(ms.Module)
+	restartBackOff := policy.newBackOff()
+
+	for restarts := 0; ; restarts++ {
+		err := k.runModuleOnce(name, ms, policy, s.ctx)
+
+		if err != nil {
+			k.logger.Error(err, "error running module "+name)
+		}
+		ms.setErr(err)
+
+		if s.ctx.Err() != nil || !policy.shouldRestart(err, restarts) {
+			return err
+		}
+
+		wait := restartBackOff.NextBackOff()
+		k.logger.Warnf("restarting module %s in %v after %d restarts", name, wait, restarts)
+
+		k.metric.WriteOne(&mon.MetricDatum{
+			Priority:   mon.PriorityHigh,
+			MetricName: metricNameModuleRestartCount,
+			Dimensions: map[string]string{
+				"Module": name,
+			},
+			Unit:  mon.UnitCount,
+			Value: 1.0,
+		})
+
+		select {
+		case <-s.ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+
+		ms.setErr(nil)
 	}
-	ms.Err = err
+}
 
-	return err
+// runModuleOnce converts a panic of a restartable module into an error, so the module can be restarted
+func (k *kernel) runModuleOnce(name string, ms *ModuleState, policy RestartPolicy, ctx context.Context) (err error) {
+	if policy.Type == RestartPolicyNever {
+		return ms.Module.Run(ctx)
+	}
+
+	defer func() {
+		panicErr := coffin.ResolveRecovery(recover())
+
+		if panicErr != nil {
+			err = fmt.Errorf("panic during running of module %s: %v", name, panicErr)
+		}
+	}()
+
+	return ms.Module.Run(ctx)
+}
+
+func (k *kernel) getDependencies() map[string][]string {
+	dependencies := make(map[string][]string)
+
+	k.modules.Range(func(name interface{}, moduleState interface{}) bool {
+		ms := moduleState.(*ModuleState)
+		dependencies[name.(string)] = getModuleDependencies(ms.Module)
+
+		return true
+	})
+
+	return dependencies
 }
 
 func (k *kernel) hasForegroundModules() bool {
@@ -243,7 +422,7 @@ func (k *kernel) doCheckRunningEssentialModules() {
 		ms := moduleState.(*ModuleState)
 		rt := ms.Module.GetType()
 
-		if !ms.IsRunning && rt == TypeEssential {
+		if !ms.isRunning() && rt == TypeEssential {
 			reason = fmt.Sprintf("the essential module [%s] has stopped running", name)
 			hasEssentialModuleStopped = true
 			return false
@@ -274,7 +453,7 @@ func (k *kernel) checkRunningForegroundModules() {
 	k.modules.Range(func(name interface{}, moduleState interface{}) bool {
 		ms := moduleState.(*ModuleState)
 
-		if ms.IsRunning && isForegroundModule(ms.Module) {
+		if ms.isRunning() && isForegroundModule(ms.Module) {
 			hasForegroundModuleRunning = true
 			return false
 		}
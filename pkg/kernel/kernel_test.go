@@ -9,12 +9,15 @@ import (
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"sync"
 	"testing"
+	"time"
 )
 
 func createMocks() (*cfgMocks.Config, *monMocks.Logger, *kernelMocks.Module) {
 	config := new(cfgMocks.Config)
 	config.On("AllKeys").Return([]string{})
+	config.On("IsSet", "kernel_shutdown_timeout").Return(false)
 
 	logger := new(monMocks.Logger)
 	logger.On("WithChannel", mock.Anything).Return(logger)
@@ -128,3 +131,239 @@ func TestRunningType(t *testing.T) {
 	mf.AssertExpectations(t)
 	mb.AssertExpectations(t)
 }
+
+type dependentModule struct {
+	*kernelMocks.Module
+	dependencies []string
+}
+
+func (m dependentModule) GetDependencies() []string {
+	return m.dependencies
+}
+
+func TestDependencyOrder(t *testing.T) {
+	config, logger, _ := createMocks()
+	k := kernel.NewWithInterfaces(config, logger)
+
+	lck := sync.Mutex{}
+	events := make([]string, 0)
+	record := func(event string) {
+		lck.Lock()
+		defer lck.Unlock()
+
+		events = append(events, event)
+	}
+
+	daemon := new(kernelMocks.Module)
+	daemon.On("GetType").Return(kernel.TypeBackground)
+	daemon.On("Boot", config, logger).Return(nil)
+	daemon.On("Run", mock.Anything).Run(func(args mock.Arguments) {
+		ctx := args.Get(0).(context.Context)
+
+		record("daemon running")
+		<-ctx.Done()
+		record("daemon stopped")
+	}).Return(nil)
+
+	consumer := dependentModule{
+		Module:       new(kernelMocks.Module),
+		dependencies: []string{"daemon"},
+	}
+	consumer.On("GetType").Return(kernel.TypeForeground)
+	consumer.On("Boot", config, logger).Return(nil)
+	consumer.On("Run", mock.Anything).Run(func(args mock.Arguments) {
+		record("consumer running")
+		record("consumer stopped")
+	}).Return(nil)
+
+	k.Add("consumer", consumer)
+	k.Add("daemon", daemon)
+	k.Run()
+
+	assert.Equal(t, []string{"daemon running", "consumer running", "consumer stopped", "daemon stopped"}, events)
+	daemon.AssertExpectations(t)
+	consumer.AssertExpectations(t)
+}
+
+func TestDependencyCycle(t *testing.T) {
+	config, logger, _ := createMocks()
+	logger.On("Error", mock.Anything, "error during the boot process of the kernel")
+
+	a := dependentModule{
+		Module:       new(kernelMocks.Module),
+		dependencies: []string{"b"},
+	}
+	a.On("GetType").Return(kernel.TypeForeground)
+
+	b := dependentModule{
+		Module:       new(kernelMocks.Module),
+		dependencies: []string{"a"},
+	}
+	b.On("GetType").Return(kernel.TypeForeground)
+
+	k := kernel.NewWithInterfaces(config, logger)
+	k.Add("a", a)
+	k.Add("b", b)
+	k.Run()
+
+	logger.AssertCalled(t, "Error", mock.Anything, "error during the boot process of the kernel")
+	a.AssertNotCalled(t, "Boot", mock.Anything, mock.Anything)
+	b.AssertNotCalled(t, "Boot", mock.Anything, mock.Anything)
+}
+
+type stopTimeoutModule struct {
+	*kernelMocks.Module
+	timeout time.Duration
+}
+
+func (m stopTimeoutModule) GetStopTimeout() time.Duration {
+	return m.timeout
+}
+
+func TestStopTimeout(t *testing.T) {
+	config, logger, _ := createMocks()
+	logger.On("Error", mock.Anything, "kernel was not able to shutdown all modules")
+
+	k := kernel.NewWithInterfaces(config, logger)
+	blocked := make(chan struct{})
+	defer close(blocked)
+
+	mb := stopTimeoutModule{
+		Module:  new(kernelMocks.Module),
+		timeout: 10 * time.Millisecond,
+	}
+	mb.On("GetType").Return(kernel.TypeBackground)
+	mb.On("Boot", config, logger).Return(nil)
+	mb.On("Run", mock.Anything).Run(func(args mock.Arguments) {
+		<-blocked
+	}).Return(nil)
+
+	mf := new(kernelMocks.Module)
+	mf.On("GetType").Return(kernel.TypeForeground)
+	mf.On("Boot", config, logger).Return(nil)
+	mf.On("Run", mock.Anything).Return(nil)
+
+	k.Add("blocking", mb)
+	k.Add("foreground", mf)
+	k.Run()
+
+	logger.AssertCalled(t, "Error", mock.Anything, "kernel was not able to shutdown all modules")
+}
+
+type dependentStopTimeoutModule struct {
+	stopTimeoutModule
+	dependencies []string
+}
+
+func (m dependentStopTimeoutModule) GetDependencies() []string {
+	return m.dependencies
+}
+
+func TestStopTimeoutPerStage(t *testing.T) {
+	config, logger, _ := createMocks()
+	logger.On("Error", mock.Anything, "kernel was not able to shutdown all modules")
+
+	k := kernel.NewWithInterfaces(config, logger)
+	slowStop := func(args mock.Arguments) {
+		ctx := args.Get(0).(context.Context)
+
+		<-ctx.Done()
+		time.Sleep(120 * time.Millisecond)
+	}
+
+	daemon := stopTimeoutModule{
+		Module:  new(kernelMocks.Module),
+		timeout: 200 * time.Millisecond,
+	}
+	daemon.On("GetType").Return(kernel.TypeBackground)
+	daemon.On("Boot", config, logger).Return(nil)
+	daemon.On("Run", mock.Anything).Run(slowStop).Return(nil)
+
+	worker := dependentStopTimeoutModule{
+		stopTimeoutModule: stopTimeoutModule{
+			Module:  new(kernelMocks.Module),
+			timeout: 200 * time.Millisecond,
+		},
+		dependencies: []string{"daemon"},
+	}
+	worker.On("GetType").Return(kernel.TypeBackground)
+	worker.On("Boot", config, logger).Return(nil)
+	worker.On("Run", mock.Anything).Run(slowStop).Return(nil)
+
+	mf := new(kernelMocks.Module)
+	mf.On("GetType").Return(kernel.TypeForeground)
+	mf.On("Boot", config, logger).Return(nil)
+	mf.On("Run", mock.Anything).Return(nil)
+
+	k.Add("daemon", daemon)
+	k.Add("worker", worker)
+	k.Add("foreground", mf)
+	k.Run()
+
+	logger.AssertNotCalled(t, "Error", mock.Anything, "kernel was not able to shutdown all modules")
+	daemon.AssertExpectations(t)
+	worker.AssertExpectations(t)
+}
+
+type restartableModule struct {
+	*kernelMocks.Module
+	policy kernel.RestartPolicy
+}
+
+func (m restartableModule) GetRestartPolicy() kernel.RestartPolicy {
+	return m.policy
+}
+
+func TestRestartOnFailure(t *testing.T) {
+	config, logger, _ := createMocks()
+	logger.On("Error", mock.Anything, "error running module restartable")
+	logger.On("Warnf", mock.Anything, "restartable", mock.Anything, mock.Anything)
+
+	k := kernel.NewWithInterfaces(config, logger)
+
+	module := restartableModule{
+		Module: new(kernelMocks.Module),
+		policy: kernel.RestartPolicy{
+			Type:            kernel.RestartPolicyOnFailure,
+			InitialInterval: time.Millisecond,
+		},
+	}
+	module.On("GetType").Return(kernel.TypeForeground)
+	module.On("Boot", config, logger).Return(nil)
+	module.On("Run", mock.Anything).Return(errors.New("failure")).Once()
+	module.On("Run", mock.Anything).Run(func(args mock.Arguments) {
+		panic("panic")
+	}).Once()
+	module.On("Run", mock.Anything).Return(nil).Once()
+
+	k.Add("restartable", module)
+	k.Run()
+
+	module.AssertNumberOfCalls(t, "Run", 3)
+	logger.AssertNumberOfCalls(t, "Warnf", 2)
+}
+
+func TestRestartMaxRestarts(t *testing.T) {
+	config, logger, _ := createMocks()
+	logger.On("Error", mock.Anything, mock.Anything)
+	logger.On("Warnf", mock.Anything, "restartable", mock.Anything, mock.Anything)
+
+	k := kernel.NewWithInterfaces(config, logger)
+
+	module := restartableModule{
+		Module: new(kernelMocks.Module),
+		policy: kernel.RestartPolicy{
+			Type:            kernel.RestartPolicyAlways,
+			MaxRestarts:     2,
+			InitialInterval: time.Millisecond,
+		},
+	}
+	module.On("GetType").Return(kernel.TypeForeground)
+	module.On("Boot", config, logger).Return(nil)
+	module.On("Run", mock.Anything).Return(nil)
+
+	k.Add("restartable", module)
+	k.Run()
+
+	module.AssertNumberOfCalls(t, "Run", 3)
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"github.com/applike/gosoline/pkg/cfg"
 	"github.com/applike/gosoline/pkg/mon"
+	"sync"
+	"time"
 )
 
 const (
@@ -20,6 +22,37 @@ type ModuleState struct {
 	Module    Module
 	IsRunning bool
 	Err       error
+
+	// lck guards IsRunning and Err, they are written by the goroutine running the module and read by the health checks
+	lck sync.RWMutex
+}
+
+func (s *ModuleState) setRunning(isRunning bool) {
+	s.lck.Lock()
+	defer s.lck.Unlock()
+
+	s.IsRunning = isRunning
+}
+
+func (s *ModuleState) setErr(err error) {
+	s.lck.Lock()
+	defer s.lck.Unlock()
+
+	s.Err = err
+}
+
+func (s *ModuleState) isRunning() bool {
+	s.lck.RLock()
+	defer s.lck.RUnlock()
+
+	return s.IsRunning
+}
+
+func (s *ModuleState) snapshot() (isRunning bool, err error) {
+	s.lck.RLock()
+	defer s.lck.RUnlock()
+
+	return s.IsRunning, s.Err
 }
 
 //go:generate mockery -name=Module
@@ -29,6 +62,18 @@ type Module interface {
 	Run(ctx context.Context) error
 }
 
+// DependentModule can be implemented by a module which requires other modules to be booted and
+// running before it is started itself. The kernel stops it again before any of its dependencies.
+type DependentModule interface {
+	GetDependencies() []string
+}
+
+// StopTimeoutModule can be implemented by a module which needs a different amount of time to
+// finish its work after its context was canceled than configured by kernel_shutdown_timeout.
+type StopTimeoutModule interface {
+	GetStopTimeout() time.Duration
+}
+
 type ModuleFactory func(config cfg.Config, logger mon.Logger) (map[string]Module, error)
 
 type EssentialModule struct {
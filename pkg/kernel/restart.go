@@ -0,0 +1,72 @@
+package kernel
+
+import (
+	"github.com/cenkalti/backoff"
+	"time"
+)
+
+const (
+	RestartPolicyNever     = "never"
+	RestartPolicyOnFailure = "on-failure"
+	RestartPolicyAlways    = "always"
+)
+
+const metricNameModuleRestartCount = "KernelModuleRestartCount"
+
+type RestartPolicy struct {
+	Type string
+	// MaxRestarts limits the number of restarts, 0 means unlimited
+	MaxRestarts     int
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+}
+
+// RestartableModule can be implemented by a module which should be run again after its Run function returned.
+// The kernel waits with an exponential backoff between the restarts.
+type RestartableModule interface {
+	GetRestartPolicy() RestartPolicy
+}
+
+func getRestartPolicy(m Module) RestartPolicy {
+	rm, ok := m.(RestartableModule)
+
+	if !ok {
+		return RestartPolicy{
+			Type: RestartPolicyNever,
+		}
+	}
+
+	return rm.GetRestartPolicy()
+}
+
+func (p RestartPolicy) shouldRestart(err error, restarts int) bool {
+	if p.MaxRestarts > 0 && restarts >= p.MaxRestarts {
+		return false
+	}
+
+	switch p.Type {
+	case RestartPolicyAlways:
+		return true
+	case RestartPolicyOnFailure:
+		return err != nil
+	default:
+		return false
+	}
+}
+
+func (p RestartPolicy) newBackOff() backoff.BackOff {
+	backoffConfig := backoff.NewExponentialBackOff()
+	backoffConfig.MaxElapsedTime = 0
+
+	if p.InitialInterval > 0 {
+		backoffConfig.InitialInterval = p.InitialInterval
+	}
+
+	if p.MaxInterval > 0 {
+		backoffConfig.MaxInterval = p.MaxInterval
+	}
+
+	backoffConfig.Reset()
+
+	return backoffConfig
+}
@@ -0,0 +1,103 @@
+package kernel
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/coffin"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type stage struct {
+	index   int
+	modules []string
+
+	cfn coffin.Coffin
+	ctx context.Context
+	wg  sync.WaitGroup
+}
+
+func newStage(index int, modules []string) *stage {
+	s := &stage{
+		index:   index,
+		modules: modules,
+	}
+
+	s.cfn, s.ctx = coffin.WithContext(context.Background())
+
+	return s
+}
+
+func getModuleDependencies(m Module) []string {
+	dm, ok := m.(DependentModule)
+
+	if !ok {
+		return []string{}
+	}
+
+	return dm.GetDependencies()
+}
+
+// buildStages sorts the modules topologically: every module is placed into the first stage
+// after all the stages containing the modules it depends on.
+func buildStages(dependencies map[string][]string) ([]*stage, error) {
+	for name, deps := range dependencies {
+		for _, dep := range deps {
+			if _, ok := dependencies[dep]; !ok {
+				return nil, fmt.Errorf("module %s depends on the unknown module %s", name, dep)
+			}
+		}
+	}
+
+	stages := make([]*stage, 0)
+	placed := make(map[string]bool)
+
+	for len(placed) < len(dependencies) {
+		names := make([]string, 0)
+
+		for name, deps := range dependencies {
+			if !placed[name] && allPlaced(placed, deps) {
+				names = append(names, name)
+			}
+		}
+
+		if len(names) == 0 {
+			return nil, fmt.Errorf("there is a dependency cycle between the modules %s", strings.Join(unplaced(placed, dependencies), ", "))
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			placed[name] = true
+		}
+
+		stages = append(stages, newStage(len(stages), names))
+	}
+
+	return stages, nil
+}
+
+func allPlaced(placed map[string]bool, names []string) bool {
+	for _, name := range names {
+		if !placed[name] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func unplaced(placed map[string]bool, dependencies map[string][]string) []string {
+	names := make([]string, 0)
+
+	for name := range dependencies {
+		if !placed[name] {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -234,3 +234,156 @@ func (_m *Client) Set(key string, value interface{}, expiration time.Duration) e
 
 	return r0
 }
+
+// XAck provides a mock function with given fields: stream, group, ids
+func (_m *Client) XAck(stream string, group string, ids ...string) (int64, error) {
+	_va := make([]interface{}, len(ids))
+	for _i := range ids {
+		_va[_i] = ids[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, stream, group)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(string, string, ...string) int64); ok {
+		r0 = rf(stream, group, ids...)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, ...string) error); ok {
+		r1 = rf(stream, group, ids...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// XAdd provides a mock function with given fields: args
+func (_m *Client) XAdd(args *go_redisredis.XAddArgs) (string, error) {
+	ret := _m.Called(args)
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func(*go_redisredis.XAddArgs) string); ok {
+		r0 = rf(args)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*go_redisredis.XAddArgs) error); ok {
+		r1 = rf(args)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// XClaim provides a mock function with given fields: args
+func (_m *Client) XClaim(args *go_redisredis.XClaimArgs) ([]go_redisredis.XMessage, error) {
+	ret := _m.Called(args)
+
+	var r0 []go_redisredis.XMessage
+	if rf, ok := ret.Get(0).(func(*go_redisredis.XClaimArgs) []go_redisredis.XMessage); ok {
+		r0 = rf(args)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]go_redisredis.XMessage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*go_redisredis.XClaimArgs) error); ok {
+		r1 = rf(args)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// XGroupCreateMkStream provides a mock function with given fields: stream, group, start
+func (_m *Client) XGroupCreateMkStream(stream string, group string, start string) error {
+	ret := _m.Called(stream, group, start)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string, string) error); ok {
+		r0 = rf(stream, group, start)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// XLen provides a mock function with given fields: stream
+func (_m *Client) XLen(stream string) (int64, error) {
+	ret := _m.Called(stream)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(string) int64); ok {
+		r0 = rf(stream)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(stream)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// XPendingExt provides a mock function with given fields: args
+func (_m *Client) XPendingExt(args *go_redisredis.XPendingExtArgs) ([]go_redisredis.XPendingExt, error) {
+	ret := _m.Called(args)
+
+	var r0 []go_redisredis.XPendingExt
+	if rf, ok := ret.Get(0).(func(*go_redisredis.XPendingExtArgs) []go_redisredis.XPendingExt); ok {
+		r0 = rf(args)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]go_redisredis.XPendingExt)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*go_redisredis.XPendingExtArgs) error); ok {
+		r1 = rf(args)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// XReadGroup provides a mock function with given fields: args
+func (_m *Client) XReadGroup(args *go_redisredis.XReadGroupArgs) ([]go_redisredis.XStream, error) {
+	ret := _m.Called(args)
+
+	var r0 []go_redisredis.XStream
+	if rf, ok := ret.Get(0).(func(*go_redisredis.XReadGroupArgs) []go_redisredis.XStream); ok {
+		r0 = rf(args)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]go_redisredis.XStream)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*go_redisredis.XReadGroupArgs) error); ok {
+		r1 = rf(args)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
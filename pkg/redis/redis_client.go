@@ -32,6 +32,14 @@ type Client interface {
 	LLen(key string) (int64, error)
 	RPush(key string, values ...interface{}) (int64, error)
 
+	XAdd(args *baseRedis.XAddArgs) (string, error)
+	XAck(stream, group string, ids ...string) (int64, error)
+	XClaim(args *baseRedis.XClaimArgs) ([]baseRedis.XMessage, error)
+	XGroupCreateMkStream(stream, group, start string) error
+	XLen(stream string) (int64, error)
+	XPendingExt(args *baseRedis.XPendingExtArgs) ([]baseRedis.XPendingExt, error)
+	XReadGroup(args *baseRedis.XReadGroupArgs) ([]baseRedis.XStream, error)
+
 	HGet(key, field string) (string, error)
 	HSet(key, field string, value interface{}) error
 
@@ -156,6 +164,64 @@ func (c *redisClient) RPush(key string, values ...interface{}) (int64, error) {
 	return res.(*baseRedis.IntCmd).Result()
 }
 
+func (c *redisClient) XAdd(args *baseRedis.XAddArgs) (string, error) {
+	res := c.attemptPreventingFailuresByBackoff(func() (interface{}, error) {
+		cmd := c.base.XAdd(args)
+
+		return cmd, cmd.Err()
+	})
+
+	return res.(*baseRedis.StringCmd).Result()
+}
+
+func (c *redisClient) XAck(stream, group string, ids ...string) (int64, error) {
+	res := c.attemptPreventingFailuresByBackoff(func() (interface{}, error) {
+		cmd := c.base.XAck(stream, group, ids...)
+
+		return cmd, cmd.Err()
+	})
+
+	return res.(*baseRedis.IntCmd).Result()
+}
+
+func (c *redisClient) XClaim(args *baseRedis.XClaimArgs) ([]baseRedis.XMessage, error) {
+	res := c.attemptPreventingFailuresByBackoff(func() (interface{}, error) {
+		cmd := c.base.XClaim(args)
+
+		return cmd, cmd.Err()
+	})
+
+	return res.(*baseRedis.XMessageSliceCmd).Result()
+}
+
+func (c *redisClient) XGroupCreateMkStream(stream, group, start string) error {
+	return c.base.XGroupCreateMkStream(stream, group, start).Err()
+}
+
+func (c *redisClient) XLen(stream string) (int64, error) {
+	return c.base.XLen(stream).Result()
+}
+
+func (c *redisClient) XPendingExt(args *baseRedis.XPendingExtArgs) ([]baseRedis.XPendingExt, error) {
+	res := c.attemptPreventingFailuresByBackoff(func() (interface{}, error) {
+		cmd := c.base.XPendingExt(args)
+
+		return cmd, cmd.Err()
+	})
+
+	return res.(*baseRedis.XPendingExtCmd).Result()
+}
+
+func (c *redisClient) XReadGroup(args *baseRedis.XReadGroupArgs) ([]baseRedis.XStream, error) {
+	res := c.attemptPreventingFailuresByBackoff(func() (interface{}, error) {
+		cmd := c.base.XReadGroup(args)
+
+		return cmd, cmd.Err()
+	})
+
+	return res.(*baseRedis.XStreamSliceCmd).Result()
+}
+
 func (c *redisClient) HGet(key, field string) (string, error) {
 	return c.base.HGet(key, field).Result()
 }
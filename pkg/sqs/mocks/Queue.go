@@ -12,6 +12,20 @@ type Queue struct {
 	mock.Mock
 }
 
+// ChangeMessageVisibility provides a mock function with given fields: receiptHandle, visibilityTimeout
+func (_m *Queue) ChangeMessageVisibility(receiptHandle string, visibilityTimeout int64) error {
+	ret := _m.Called(receiptHandle, visibilityTimeout)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, int64) error); ok {
+		r0 = rf(receiptHandle, visibilityTimeout)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // DeleteMessage provides a mock function with given fields: receiptHandle
 func (_m *Queue) DeleteMessage(receiptHandle string) error {
 	ret := _m.Called(receiptHandle)
@@ -26,6 +40,29 @@ func (_m *Queue) DeleteMessage(receiptHandle string) error {
 	return r0
 }
 
+// DeleteMessageBatch provides a mock function with given fields: receiptHandles
+func (_m *Queue) DeleteMessageBatch(receiptHandles []string) ([]string, error) {
+	ret := _m.Called(receiptHandles)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func([]string) []string); ok {
+		r0 = rf(receiptHandles)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]string) error); ok {
+		r1 = rf(receiptHandles)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetArn provides a mock function with given fields:
 func (_m *Queue) GetArn() string {
 	ret := _m.Called()
@@ -40,6 +77,29 @@ func (_m *Queue) GetArn() string {
 	return r0
 }
 
+// GetMessageCounts provides a mock function with given fields:
+func (_m *Queue) GetMessageCounts() (*sqs.MessageCounts, error) {
+	ret := _m.Called()
+
+	var r0 *sqs.MessageCounts
+	if rf, ok := ret.Get(0).(func() *sqs.MessageCounts); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*sqs.MessageCounts)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetName provides a mock function with given fields:
 func (_m *Queue) GetName() string {
 	ret := _m.Called()
@@ -8,6 +8,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
 	"github.com/twinj/uuid"
+	"strconv"
 )
 
 //go:generate mockery -name Queue
@@ -15,8 +16,11 @@ type Queue interface {
 	GetName() string
 	GetUrl() string
 	GetArn() string
+	GetMessageCounts() (*MessageCounts, error)
 
+	ChangeMessageVisibility(receiptHandle string, visibilityTimeout int64) error
 	DeleteMessage(receiptHandle string) error
+	DeleteMessageBatch(receiptHandles []string) ([]string, error)
 	Receive(waitTime int64) ([]*sqs.Message, error)
 	Send(ctx context.Context, msg *Message) error
 	SendBatch(ctx context.Context, messages []*Message) error
@@ -28,6 +32,13 @@ type Message struct {
 	Body           *string
 }
 
+// MessageCounts are the approximate numbers of messages in the queue as reported by sqs
+type MessageCounts struct {
+	Visible  int64
+	InFlight int64
+	Delayed  int64
+}
+
 type FifoSettings struct {
 	Enabled                   bool `cfg:"enabled"`
 	ContentBasedDeduplication bool `cfg:"contentBasedDeduplication"`
@@ -152,6 +163,25 @@ func (q *queue) Receive(waitTime int64) ([]*sqs.Message, error) {
 	return out.Messages, nil
 }
 
+// ChangeMessageVisibility makes a received message invisible for the given number of seconds from now on,
+// a timeout of 0 makes it visible again immediately
+func (q *queue) ChangeMessageVisibility(receiptHandle string, visibilityTimeout int64) error {
+	input := &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(q.properties.Url),
+		ReceiptHandle:     aws.String(receiptHandle),
+		VisibilityTimeout: aws.Int64(visibilityTimeout),
+	}
+
+	_, err := q.client.ChangeMessageVisibility(input)
+
+	if err != nil {
+		q.logger.Errorf(err, "could not change the visibility of a message of sqs queue %s", q.properties.Name)
+		return err
+	}
+
+	return nil
+}
+
 func (q *queue) DeleteMessage(receiptHandle string) error {
 	input := &sqs.DeleteMessageInput{
 		QueueUrl:      aws.String(q.properties.Url),
@@ -168,6 +198,87 @@ func (q *queue) DeleteMessage(receiptHandle string) error {
 	return nil
 }
 
+// DeleteMessageBatch deletes up to 10 messages at once and returns the receipt handles of the messages which couldn't be deleted
+func (q *queue) DeleteMessageBatch(receiptHandles []string) ([]string, error) {
+	if len(receiptHandles) == 0 {
+		return nil, nil
+	}
+
+	entries := make([]*sqs.DeleteMessageBatchRequestEntry, len(receiptHandles))
+
+	for i, receiptHandle := range receiptHandles {
+		entries[i] = &sqs.DeleteMessageBatchRequestEntry{
+			Id:            aws.String(strconv.Itoa(i)),
+			ReceiptHandle: aws.String(receiptHandle),
+		}
+	}
+
+	input := &sqs.DeleteMessageBatchInput{
+		QueueUrl: aws.String(q.properties.Url),
+		Entries:  entries,
+	}
+
+	out, err := q.client.DeleteMessageBatch(input)
+
+	if err != nil {
+		q.logger.Errorf(err, "could not delete a batch of messages from sqs queue %s", q.properties.Name)
+		return receiptHandles, err
+	}
+
+	failed := make([]string, 0, len(out.Failed))
+
+	for _, entry := range out.Failed {
+		i, err := strconv.Atoi(aws.StringValue(entry.Id))
+
+		if err != nil || i < 0 || i >= len(receiptHandles) {
+			continue
+		}
+
+		failed = append(failed, receiptHandles[i])
+	}
+
+	return failed, nil
+}
+
+func (q *queue) GetMessageCounts() (*MessageCounts, error) {
+	input := &sqs.GetQueueAttributesInput{
+		QueueUrl: aws.String(q.properties.Url),
+		AttributeNames: []*string{
+			aws.String(sqs.QueueAttributeNameApproximateNumberOfMessages),
+			aws.String(sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible),
+			aws.String(sqs.QueueAttributeNameApproximateNumberOfMessagesDelayed),
+		},
+	}
+
+	out, err := q.client.GetQueueAttributes(input)
+
+	if err != nil {
+		q.logger.Errorf(err, "could not get the message counts of sqs queue %s", q.properties.Name)
+		return nil, err
+	}
+
+	counts := &MessageCounts{}
+	targets := map[string]*int64{
+		sqs.QueueAttributeNameApproximateNumberOfMessages:           &counts.Visible,
+		sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible: &counts.InFlight,
+		sqs.QueueAttributeNameApproximateNumberOfMessagesDelayed:    &counts.Delayed,
+	}
+
+	for name, target := range targets {
+		value, ok := out.Attributes[name]
+
+		if !ok {
+			continue
+		}
+
+		if *target, err = strconv.ParseInt(aws.StringValue(value), 10, 64); err != nil {
+			return nil, err
+		}
+	}
+
+	return counts, nil
+}
+
 func (q *queue) GetName() string {
 	return q.properties.Name
 }
@@ -0,0 +1,67 @@
+package sqs_test
+
+import (
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/sqs"
+	"github.com/applike/gosoline/pkg/sqs/mocks"
+	"github.com/aws/aws-sdk-go/aws"
+	awsSqs "github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestQueue_DeleteMessageBatch(t *testing.T) {
+	logger := monMocks.NewLoggerMockedAll()
+	client := new(mocks.SQSAPI)
+
+	client.On("DeleteMessageBatch", &awsSqs.DeleteMessageBatchInput{
+		QueueUrl: aws.String("url"),
+		Entries: []*awsSqs.DeleteMessageBatchRequestEntry{
+			{Id: aws.String("0"), ReceiptHandle: aws.String("first")},
+			{Id: aws.String("1"), ReceiptHandle: aws.String("second")},
+		},
+	}).Return(&awsSqs.DeleteMessageBatchOutput{
+		Failed: []*awsSqs.BatchResultErrorEntry{
+			{Id: aws.String("1"), Code: aws.String("InternalError")},
+		},
+	}, nil).Once()
+
+	queue := sqs.NewWithInterfaces(logger, client, &sqs.Properties{
+		Url: "url",
+	})
+
+	failed, err := queue.DeleteMessageBatch([]string{"first", "second"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"second"}, failed)
+	client.AssertExpectations(t)
+}
+
+func TestQueue_GetMessageCounts(t *testing.T) {
+	logger := monMocks.NewLoggerMockedAll()
+	client := new(mocks.SQSAPI)
+
+	client.On("GetQueueAttributes", &awsSqs.GetQueueAttributesInput{
+		QueueUrl: aws.String("url"),
+		AttributeNames: []*string{
+			aws.String(awsSqs.QueueAttributeNameApproximateNumberOfMessages),
+			aws.String(awsSqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible),
+			aws.String(awsSqs.QueueAttributeNameApproximateNumberOfMessagesDelayed),
+		},
+	}).Return(&awsSqs.GetQueueAttributesOutput{
+		Attributes: map[string]*string{
+			awsSqs.QueueAttributeNameApproximateNumberOfMessages:           aws.String("12"),
+			awsSqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible: aws.String("3"),
+		},
+	}, nil).Once()
+
+	queue := sqs.NewWithInterfaces(logger, client, &sqs.Properties{
+		Url: "url",
+	})
+
+	counts, err := queue.GetMessageCounts()
+
+	assert.NoError(t, err)
+	assert.Equal(t, &sqs.MessageCounts{Visible: 12, InFlight: 3}, counts)
+	client.AssertExpectations(t)
+}
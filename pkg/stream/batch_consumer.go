@@ -18,16 +18,35 @@ type BatchConsumerCallback interface {
 	Consume(ctx context.Context, msg []*Message) error
 }
 
-type baseBatchConsumer struct {
+// PartialBatchError can be returned by a BatchConsumerCallback if only some messages of the batch failed.
+// All messages of the batch which are not contained in Failed are acknowledged.
+type PartialBatchError struct {
+	Failed []*Message
+	Err    error
+}
+
+func (e *PartialBatchError) Error() string {
+	return fmt.Sprintf("%d messages of the batch failed: %v", len(e.Failed), e.Err)
+}
+
+type BatchConsumerSettings struct {
+	Input       string
+	IdleTimeout time.Duration
+	BatchSize   int
+}
+
+type BatchConsumer struct {
 	kernel.EssentialModule
+	ConsumerAcknowledge
 
 	logger mon.Logger
+	mw     mon.MetricWriter
 	tracer tracing.Tracer
 
-	input  Input
 	cfn    coffin.Coffin
 	ticker *time.Ticker
 
+	settings *BatchConsumerSettings
 	name     string
 	callback BatchConsumerCallback
 
@@ -35,26 +54,53 @@ type baseBatchConsumer struct {
 	processed int
 	batchSize int
 	batch     []*Message
-	force     bool
 }
 
-func (c *baseBatchConsumer) Boot(config cfg.Config, logger mon.Logger) error {
+func NewBatchConsumer(callback BatchConsumerCallback) *BatchConsumer {
+	return &BatchConsumer{
+		callback: callback,
+	}
+}
+
+func (c *BatchConsumer) Boot(config cfg.Config, logger mon.Logger) error {
+	c.callback.Boot(config, logger)
+
+	appId := cfg.GetAppIdFromConfig(config)
+	c.name = fmt.Sprintf("batch-consumer-%v-%v", appId.Family, appId.Application)
+
+	tracer := tracing.NewAwsTracer(config)
+	mw := mon.NewMetricDaemonWriter()
+
+	settings := &BatchConsumerSettings{
+		Input:       config.GetString("consumer_input"),
+		IdleTimeout: config.GetDuration("consumer_idle_timeout") * time.Second,
+		BatchSize:   config.GetInt("consumer_batch_size"),
+	}
+
+	input := NewConfigurableInput(config, logger, settings.Input)
+
+	return c.BootWithInterfaces(logger, tracer, mw, input, settings)
+}
+
+// BootWithInterfaces boots the batch consumer without booting its callback
+func (c *BatchConsumer) BootWithInterfaces(logger mon.Logger, tracer tracing.Tracer, mw mon.MetricWriter, input Input, settings *BatchConsumerSettings) error {
 	c.logger = logger
-	c.tracer = tracing.NewAwsTracer(config)
+	c.mw = mw
+	c.tracer = tracer
+	c.settings = settings
 	c.cfn = coffin.New()
+	c.ticker = time.NewTicker(settings.IdleTimeout)
 
-	idleTimeout := config.GetDuration("consumer_idle_timeout")
-	c.ticker = time.NewTicker(idleTimeout * time.Second)
-
-	c.batchSize = config.GetInt("consumer_batch_size")
+	c.batchSize = settings.BatchSize
 	c.batch = make([]*Message, 0, c.batchSize)
 
-	c.callback.Boot(config, logger)
+	c.input = input
+	c.ConsumerAcknowledge = NewConsumerAcknowledgeWithInterfaces(logger, input)
 
 	return nil
 }
 
-func (c *baseBatchConsumer) Run(ctx context.Context) error {
+func (c *BatchConsumer) Run(ctx context.Context) error {
 	defer c.logger.Info("leaving consumer ", c.name)
 
 	c.cfn.Gof(c.input.Run, "panic during run of the consumer input")
@@ -64,21 +110,26 @@ func (c *baseBatchConsumer) Run(ctx context.Context) error {
 		select {
 		case <-ctx.Done():
 			c.input.Stop()
-			return c.cfn.Wait()
+			err := c.cfn.Wait()
+			c.consumeBatch()
+			c.FlushAcknowledgements(context.Background())
+
+			return err
 
 		case <-c.cfn.Dead():
+			c.consumeBatch()
+			c.FlushAcknowledgements(context.Background())
+
 			return c.cfn.Err()
 
 		case <-c.ticker.C:
 			c.consumeBatch()
-
-			c.logger.Info(fmt.Sprintf("processed %v messages", c.processed))
-			c.processed = 0
+			c.logProcessed()
 		}
 	}
 }
 
-func (c *baseBatchConsumer) consume() error {
+func (c *BatchConsumer) consume() error {
 	for {
 		msg, ok := <-c.input.Data()
 
@@ -86,28 +137,79 @@ func (c *baseBatchConsumer) consume() error {
 			return nil
 		}
 
+		writeMessageAgeMetric(c.mw, c.settings.Input, msg)
+
 		c.m.Lock()
 		c.batch = append(c.batch, msg)
+		full := len(c.batch) >= c.batchSize
 		c.m.Unlock()
 
-		if len(c.batch) < c.batchSize {
-			continue
+		if full {
+			c.consumeBatch()
 		}
-
-		c.consumeBatch()
 	}
 }
 
-func (c *baseBatchConsumer) consumeBatch() {
+func (c *BatchConsumer) consumeBatch() {
+	c.m.Lock()
+	defer c.m.Unlock()
+
 	if len(c.batch) == 0 {
 		return
 	}
 
-	c.m.Lock()
-	defer c.m.Unlock()
+	batch := c.batch
+	c.batch = make([]*Message, 0, c.batchSize)
 
-	_ = c.callback.Consume(context.Background(), c.batch)
+	ctx, trans := c.tracer.StartSpan(c.name)
+	defer trans.Finish()
 
-	c.processed += len(c.batch)
-	c.batch = make([]*Message, 0, c.batchSize)
+	err := c.callback.Consume(ctx, batch)
+	c.processed += len(batch)
+
+	if err == nil {
+		c.acknowledgeBatch(ctx, batch, nil)
+		return
+	}
+
+	if partialErr, ok := err.(*PartialBatchError); ok {
+		c.logger.WithContext(ctx).Error(partialErr, "an error occurred during the consume operation of a part of the batch")
+		c.acknowledgeBatch(ctx, batch, partialErr.Failed)
+
+		return
+	}
+
+	c.logger.WithContext(ctx).Error(err, "an error occurred during the consume operation of the batch")
+	c.acknowledgeBatch(ctx, batch, batch)
+}
+
+// acknowledgeBatch acknowledges all messages of the batch and returns the failed ones to the input
+func (c *BatchConsumer) acknowledgeBatch(ctx context.Context, batch []*Message, failed []*Message) {
+	isFailed := make(map[*Message]bool, len(failed))
+
+	for _, msg := range failed {
+		isFailed[msg] = true
+	}
+
+	succeeded := make([]*Message, 0, len(batch))
+
+	for _, msg := range batch {
+		if isFailed[msg] {
+			c.Nack(ctx, msg, NackDefaultDelay)
+			continue
+		}
+
+		succeeded = append(succeeded, msg)
+	}
+
+	c.AcknowledgeBatch(ctx, succeeded)
+}
+
+func (c *BatchConsumer) logProcessed() {
+	c.m.Lock()
+	processed := c.processed
+	c.processed = 0
+	c.m.Unlock()
+
+	c.logger.Info(fmt.Sprintf("processed %v messages", processed))
 }
@@ -0,0 +1,79 @@
+package stream_test
+
+import (
+	"context"
+	"errors"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	streamMocks "github.com/applike/gosoline/pkg/stream/mocks"
+	"github.com/applike/gosoline/pkg/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func runBatchConsumer(t *testing.T, callback stream.BatchConsumerCallback, batchSize int, messages ...*stream.Message) *stream.InMemoryInput {
+	logger := monMocks.NewLoggerMockedAll()
+	mw := monMocks.NewMetricWriterMockedAll()
+	input := stream.NewInMemoryInput(&stream.InMemorySettings{})
+
+	consumer := stream.NewBatchConsumer(callback)
+	err := consumer.BootWithInterfaces(logger, tracing.NewNoopTracer(), mw, input, &stream.BatchConsumerSettings{
+		IdleTimeout: time.Hour,
+		BatchSize:   batchSize,
+	})
+	assert.NoError(t, err, "the batch consumer should boot without an error")
+
+	input.Publish(messages...)
+	go input.Stop()
+
+	err = consumer.Run(context.Background())
+	assert.NoError(t, err, "the batch consumer should run without an error")
+
+	return input
+}
+
+func TestBatchConsumer_Run(t *testing.T) {
+	msg1 := &stream.Message{Body: "1"}
+	msg2 := &stream.Message{Body: "2"}
+	msg3 := &stream.Message{Body: "3"}
+
+	callback := new(streamMocks.BatchConsumerCallback)
+	callback.On("Consume", mock.Anything, []*stream.Message{msg1, msg2}).Return(nil).Once()
+	callback.On("Consume", mock.Anything, []*stream.Message{msg3}).Return(nil).Once()
+
+	input := runBatchConsumer(t, callback, 2, msg1, msg2, msg3)
+
+	callback.AssertExpectations(t)
+	assert.Len(t, input.Acks(), 3, "all messages should be acknowledged")
+}
+
+func TestBatchConsumer_RunPartialFailure(t *testing.T) {
+	msg1 := &stream.Message{Body: "1"}
+	msg2 := &stream.Message{Body: "2"}
+
+	callback := new(streamMocks.BatchConsumerCallback)
+	callback.On("Consume", mock.Anything, []*stream.Message{msg1, msg2}).Return(&stream.PartialBatchError{
+		Failed: []*stream.Message{msg2},
+		Err:    errors.New("failure"),
+	}).Once()
+
+	input := runBatchConsumer(t, callback, 2, msg1, msg2)
+
+	callback.AssertExpectations(t)
+	assert.True(t, input.IsAcked(msg1), "the successful message should be acknowledged")
+	assert.False(t, input.IsAcked(msg2), "the failed message should not be acknowledged")
+}
+
+func TestBatchConsumer_RunFailure(t *testing.T) {
+	msg := &stream.Message{Body: "1"}
+
+	callback := new(streamMocks.BatchConsumerCallback)
+	callback.On("Consume", mock.Anything, []*stream.Message{msg}).Return(errors.New("failure")).Once()
+
+	input := runBatchConsumer(t, callback, 10, msg)
+
+	callback.AssertExpectations(t)
+	assert.Empty(t, input.Acks(), "no message of a failed batch should be acknowledged")
+}
@@ -2,6 +2,7 @@ package stream
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/applike/gosoline/pkg/cfg"
 	"github.com/applike/gosoline/pkg/coffin"
@@ -12,7 +13,19 @@ import (
 	"time"
 )
 
-const metricNameConsumerProcessedCount = "ConsumerProcessedCount"
+const (
+	metricNameConsumerProcessedCount  = "ConsumerProcessedCount"
+	metricNameConsumerRetryCount      = "ConsumerRetryCount"
+	metricNameConsumerDeadLetterCount = "ConsumerDeadLetterCount"
+	metricNameConsumerTimeoutCount    = "ConsumerTimeoutCount"
+)
+
+const defaultConsumerRunnerCount = 10
+
+var (
+	errConsumeTimeout = errors.New("the consume operation did not finish in time")
+	errConsumeStopped = errors.New("the consumer stopped before the consume operation was retried")
+)
 
 //go:generate mockery -name=ConsumerCallback
 type ConsumerCallback interface {
@@ -20,19 +33,37 @@ type ConsumerCallback interface {
 	Consume(ctx context.Context, msg *Message) (bool, error)
 }
 
+type ConsumerSettings struct {
+	Input       string
+	RunnerCount int
+	IdleTimeout time.Duration
+	// ConsumeTimeout cancels the context of the callback for a single message, 0 disables the timeout
+	ConsumeTimeout time.Duration
+	Retry          ConsumerRetrySettings
+	// Partitioned routes all messages with the same partition key to the same runner, so they are processed in order
+	Partitioned        bool
+	PartitionAttribute string
+	// NackDelay returns messages which were not acknowledged to the input after the delay, 0 keeps the default delay of the input
+	NackDelay time.Duration
+}
+
 type Consumer struct {
 	kernel.EssentialModule
 	ConsumerAcknowledge
 
-	logger mon.Logger
-	mw     mon.MetricWriter
-	tracer tracing.Tracer
-	cfn    coffin.Coffin
-	ticker *time.Ticker
-
-	name      string
-	callback  ConsumerCallback
-	processed int32
+	logger     mon.Logger
+	mw         mon.MetricWriter
+	tracer     tracing.Tracer
+	cfn        coffin.Coffin
+	ticker     *time.Ticker
+	deadLetter Output
+	settings   *ConsumerSettings
+
+	name        string
+	callback    ConsumerCallback
+	middlewares []ConsumerMiddleware
+	handler     ConsumeHandler
+	processed   int32
 }
 
 func NewConsumer(callback ConsumerCallback) *Consumer {
@@ -52,42 +83,103 @@ func (c *Consumer) Boot(config cfg.Config, logger mon.Logger) error {
 	appId := cfg.GetAppIdFromConfig(config)
 	c.name = fmt.Sprintf("consumer-%v-%v", appId.Family, appId.Application)
 
-	c.logger = logger
-	c.tracer = tracing.NewAwsTracer(config)
+	tracer := tracing.NewAwsTracer(config)
 
 	defaultMetrics := getConsumerDefaultMetrics()
-	c.mw = mon.NewMetricDaemonWriter(defaultMetrics...)
+	mw := mon.NewMetricDaemonWriter(defaultMetrics...)
+
+	settings := &ConsumerSettings{
+		Input:       config.GetString("consumer_input"),
+		RunnerCount: defaultConsumerRunnerCount,
+		IdleTimeout: config.GetDuration("consumer_idle_timeout") * time.Second,
+		Retry:       readConsumerRetrySettings(config),
+	}
+
+	if config.IsSet("consumer_runner_count") {
+		settings.RunnerCount = config.GetInt("consumer_runner_count")
+	}
+
+	if config.IsSet("consumer_partitioned") {
+		settings.Partitioned = config.GetBool("consumer_partitioned")
+	}
+
+	if config.IsSet("consumer_partition_attribute") {
+		settings.PartitionAttribute = config.GetString("consumer_partition_attribute")
+	}
+
+	if config.IsSet("consumer_consume_timeout") {
+		settings.ConsumeTimeout = config.GetDuration("consumer_consume_timeout") * time.Second
+	}
+
+	if config.IsSet("consumer_nack_delay") {
+		settings.NackDelay = config.GetDuration("consumer_nack_delay") * time.Second
+	}
 
-	idleTimeout := config.GetDuration("consumer_idle_timeout")
-	c.ticker = time.NewTicker(idleTimeout * time.Second)
+	input := NewConfigurableInput(config, logger, settings.Input)
+	c.Use(readConsumerMiddlewares(config, logger, settings.Input)...)
 
-	inputName := config.GetString("consumer_input")
-	input := NewConfigurableInput(config, logger, inputName)
+	var deadLetter Output
+	if config.IsSet("consumer_dead_letter_output") {
+		deadLetter = NewConfigurableOutput(config, logger, config.GetString("consumer_dead_letter_output"))
+	}
+
+	return c.BootWithInterfaces(logger, tracer, mw, input, deadLetter, settings)
+}
+
+// BootWithInterfaces boots the consumer without booting its callback. The dead letter output is optional.
+func (c *Consumer) BootWithInterfaces(logger mon.Logger, tracer tracing.Tracer, mw mon.MetricWriter, input Input, deadLetter Output, settings *ConsumerSettings) error {
+	c.logger = logger
+	c.tracer = tracer
+	c.mw = mw
+	c.ticker = time.NewTicker(settings.IdleTimeout)
+	c.deadLetter = deadLetter
+	c.settings = settings
+
+	if c.settings.RunnerCount <= 0 {
+		c.settings.RunnerCount = 1
+	}
+
+	if c.settings.PartitionAttribute == "" {
+		c.settings.PartitionAttribute = AttributeSqsMessageGroupId
+	}
 
 	c.input = input
 	c.ConsumerAcknowledge = NewConsumerAcknowledgeWithInterfaces(logger, input)
+	c.handler = chainConsumerMiddlewares(c.callback.Consume, c.middlewares)
 
 	return nil
 }
 
+// Use adds middlewares around the callback, it has to be called before the consumer is booted
+func (c *Consumer) Use(middlewares ...ConsumerMiddleware) {
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
 func (c *Consumer) Run(ctx context.Context) error {
 	defer c.logger.Info("leaving consumer ", c.name)
 
 	c.cfn.Gof(c.input.Run, "panic during run of the consumer input")
 
-	for i := 0; i < 10; i++ {
-		c.cfn.Gof(c.consume, "panic during consuming")
+	if c.settings.Partitioned {
+		c.runPartitioned()
+	} else {
+		for i := 0; i < c.settings.RunnerCount; i++ {
+			c.cfn.Gof(func() error {
+				return c.consume(c.input.Data())
+			}, "panic during consuming")
+		}
 	}
 
 	for {
 		select {
 		case <-ctx.Done():
+			c.cfn.Kill(nil)
 			c.input.Stop()
-			return c.cfn.Wait()
+			return c.stop()
 
 		case <-c.cfn.Dying():
 			c.input.Stop()
-			return c.cfn.Wait()
+			return c.stop()
 
 		case <-c.ticker.C:
 			processed := atomic.SwapInt32(&c.processed, 0)
@@ -99,14 +191,31 @@ func (c *Consumer) Run(ctx context.Context) error {
 	}
 }
 
-func (c *Consumer) consume() error {
+// stop waits for the runners to finish and writes the acknowledgements the input collected for its next batch
+func (c *Consumer) stop() error {
+	err := c.cfn.Wait()
+	c.FlushAcknowledgements(context.Background())
+
+	return err
+}
+
+func (c *Consumer) CheckHealth() error {
+	if !c.cfn.Alive() {
+		return fmt.Errorf("the input of the consumer %s is not running anymore", c.name)
+	}
+
+	return nil
+}
+
+func (c *Consumer) consume(data <-chan *Message) error {
 	for {
-		msg, ok := <-c.input.Data()
+		msg, ok := <-data
 
 		if !ok {
 			return nil
 		}
 
+		writeMessageAgeMetric(c.mw, c.settings.Input, msg)
 		c.doCallback(msg)
 
 		atomic.AddInt32(&c.processed, 1)
@@ -121,19 +230,67 @@ func (c *Consumer) doCallback(msg *Message) {
 	ctx, trans := c.tracer.StartSpanFromTraceAble(msg, c.name)
 	defer trans.Finish()
 
-	ack, err := c.callback.Consume(ctx, msg)
+	ack, attempts, err := c.consumeWithRetries(ctx, msg)
+
+	if err == errConsumeTimeout {
+		c.logger.WithContext(ctx).Warnf("the consume operation did not finish within %v, the message stays unacknowledged", c.settings.ConsumeTimeout)
+		c.mw.WriteOne(&mon.MetricDatum{
+			MetricName: metricNameConsumerTimeoutCount,
+			Value:      1.0,
+		})
+
+		c.Nack(ctx, msg, c.getNackDelay())
+
+		return
+	}
+
+	if err == errConsumeStopped {
+		c.Nack(ctx, msg, c.getNackDelay())
+		return
+	}
 
 	if err != nil {
 		c.logger.WithContext(ctx).Error(err, "an error occurred during the consume operation")
 	}
 
+	if err != nil && c.deadLetter != nil {
+		ack = c.writeDeadLetter(ctx, msg, attempts, err)
+	}
+
 	if !ack {
+		c.Nack(ctx, msg, c.getNackDelay())
 		return
 	}
 
 	c.Acknowledge(ctx, msg)
 }
 
+func (c *Consumer) getNackDelay() time.Duration {
+	if c.settings.NackDelay == 0 {
+		return NackDefaultDelay
+	}
+
+	return c.settings.NackDelay
+}
+
+// consumeWithTimeout cancels the context passed to the callback as soon as the consume timeout is reached
+func (c *Consumer) consumeWithTimeout(ctx context.Context, msg *Message) (bool, error) {
+	if c.settings.ConsumeTimeout <= 0 {
+		return c.handler(ctx, msg)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, c.settings.ConsumeTimeout)
+	defer cancel()
+
+	ack, err := c.handler(timeoutCtx, msg)
+
+	if timeoutCtx.Err() == context.DeadlineExceeded {
+		return false, errConsumeTimeout
+	}
+
+	return ack, err
+}
+
 func getConsumerDefaultMetrics() mon.MetricData {
 	return mon.MetricData{
 		{
@@ -142,5 +299,23 @@ func getConsumerDefaultMetrics() mon.MetricData {
 			Unit:       mon.UnitCount,
 			Value:      0.0,
 		},
+		{
+			Priority:   mon.PriorityHigh,
+			MetricName: metricNameConsumerRetryCount,
+			Unit:       mon.UnitCount,
+			Value:      0.0,
+		},
+		{
+			Priority:   mon.PriorityHigh,
+			MetricName: metricNameConsumerDeadLetterCount,
+			Unit:       mon.UnitCount,
+			Value:      0.0,
+		},
+		{
+			Priority:   mon.PriorityHigh,
+			MetricName: metricNameConsumerTimeoutCount,
+			Unit:       mon.UnitCount,
+			Value:      0.0,
+		},
 	}
 }
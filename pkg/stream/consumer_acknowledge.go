@@ -3,6 +3,7 @@ package stream
 import (
 	"context"
 	"github.com/applike/gosoline/pkg/mon"
+	"time"
 )
 
 type ConsumerAcknowledge struct {
@@ -31,3 +32,59 @@ func (c *ConsumerAcknowledge) Acknowledge(ctx context.Context, msg *Message) {
 		c.logger.WithContext(ctx).Error(err, "could not acknowledge the message")
 	}
 }
+
+// Nack returns the message to the input after the delay, inputs which can't be acknowledged are ignored
+func (c *ConsumerAcknowledge) Nack(ctx context.Context, msg *Message, delay time.Duration) {
+	var ok bool
+	var ackInput AcknowledgeableInput
+
+	if ackInput, ok = c.input.(AcknowledgeableInput); !ok {
+		return
+	}
+
+	err := ackInput.Nack(msg, delay)
+
+	if err != nil {
+		c.logger.WithContext(ctx).Error(err, "could not return the message to the input")
+	}
+}
+
+// AcknowledgeBatch acknowledges all messages at once if the input supports it, otherwise one after another
+func (c *ConsumerAcknowledge) AcknowledgeBatch(ctx context.Context, msgs []*Message) {
+	var ok bool
+	var batchInput BatchAcknowledgeableInput
+
+	if len(msgs) == 0 {
+		return
+	}
+
+	if batchInput, ok = c.input.(BatchAcknowledgeableInput); !ok {
+		for _, msg := range msgs {
+			c.Acknowledge(ctx, msg)
+		}
+
+		return
+	}
+
+	err := batchInput.AckBatch(msgs)
+
+	if err != nil {
+		c.logger.WithContext(ctx).Error(err, "could not acknowledge the batch of messages")
+	}
+}
+
+// FlushAcknowledgements writes the acknowledgements collected by the input, it should be called after the last message was consumed
+func (c *ConsumerAcknowledge) FlushAcknowledgements(ctx context.Context) {
+	var ok bool
+	var batchInput BatchAcknowledgeableInput
+
+	if batchInput, ok = c.input.(BatchAcknowledgeableInput); !ok {
+		return
+	}
+
+	err := batchInput.FlushAcks()
+
+	if err != nil {
+		c.logger.WithContext(ctx).Error(err, "could not flush the acknowledgements")
+	}
+}
@@ -0,0 +1,91 @@
+package stream
+
+import (
+	"fmt"
+	"hash/fnv"
+)
+
+// PartitionedConsumerCallback can be implemented by a ConsumerCallback to provide the partition key of a message
+// itself instead of reading it from the partition attribute of the message.
+type PartitionedConsumerCallback interface {
+	ConsumerCallback
+	GetPartitionKey(msg *Message) string
+}
+
+// TypedPartitionedConsumerCallback is the counterpart of PartitionedConsumerCallback for a TypedConsumerCallback.
+// The partition key is taken from the message before its body gets decoded.
+type TypedPartitionedConsumerCallback interface {
+	TypedConsumerCallback
+	GetPartitionKey(msg *Message) string
+}
+
+// runPartitioned starts a runner with its own channel for every partition and a dispatcher distributing the messages.
+// Messages with the same partition key are always handed to the same runner, messages without a key to any of them.
+func (c *Consumer) runPartitioned() {
+	partitions := make([]chan *Message, c.settings.RunnerCount)
+
+	for i := range partitions {
+		partition := make(chan *Message)
+		partitions[i] = partition
+
+		c.cfn.Gof(func() error {
+			return c.consume(partition)
+		}, "panic during consuming partition %d", i)
+	}
+
+	c.cfn.Gof(func() error {
+		return c.dispatch(partitions)
+	}, "panic during dispatching messages to the partitions")
+}
+
+func (c *Consumer) dispatch(partitions []chan *Message) error {
+	defer func() {
+		for _, partition := range partitions {
+			close(partition)
+		}
+	}()
+
+	next := 0
+
+	for msg := range c.input.Data() {
+		key := c.getPartitionKey(msg)
+
+		if key == "" {
+			next = (next + 1) % len(partitions)
+			partitions[next] <- msg
+
+			continue
+		}
+
+		partitions[getPartition(key, len(partitions))] <- msg
+	}
+
+	return nil
+}
+
+func (c *Consumer) getPartitionKey(msg *Message) string {
+	if pc, ok := c.callback.(PartitionedConsumerCallback); ok {
+		return pc.GetPartitionKey(msg)
+	}
+
+	if tc, ok := c.callback.(*typedConsumerCallback); ok {
+		if pc, ok := tc.callback.(TypedPartitionedConsumerCallback); ok {
+			return pc.GetPartitionKey(msg)
+		}
+	}
+
+	key, ok := msg.Attributes[c.settings.PartitionAttribute]
+
+	if !ok || key == nil {
+		return ""
+	}
+
+	return fmt.Sprint(key)
+}
+
+func getPartition(key string, partitionCount int) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+
+	return int(hash.Sum32() % uint32(partitionCount))
+}
@@ -0,0 +1,130 @@
+package stream
+
+import (
+	"context"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/cenkalti/backoff"
+	"time"
+)
+
+const (
+	AttributeDeadLetterError    = "deadLetterError"
+	AttributeDeadLetterAttempts = "deadLetterAttempts"
+	AttributeDeadLetterConsumer = "deadLetterConsumer"
+)
+
+type ConsumerRetrySettings struct {
+	// Attempts is the number of times the callback is called for a message, values below 2 disable retries
+	Attempts int `cfg:"attempts"`
+	// InitialInterval and MaxInterval are configured in seconds
+	InitialInterval time.Duration `cfg:"initial_interval"`
+	MaxInterval     time.Duration `cfg:"max_interval"`
+}
+
+func readConsumerRetrySettings(config cfg.Config) ConsumerRetrySettings {
+	settings := ConsumerRetrySettings{
+		Attempts: 1,
+	}
+
+	if config.IsSet("consumer_retry") {
+		config.UnmarshalKey("consumer_retry", &settings)
+	}
+
+	settings.InitialInterval = settings.InitialInterval * time.Second
+	settings.MaxInterval = settings.MaxInterval * time.Second
+
+	if settings.InitialInterval <= 0 {
+		settings.InitialInterval = 100 * time.Millisecond
+	}
+
+	if settings.MaxInterval <= 0 {
+		settings.MaxInterval = 10 * time.Second
+	}
+
+	return settings
+}
+
+func (s ConsumerRetrySettings) newBackOff() backoff.BackOff {
+	backoffConfig := backoff.NewExponentialBackOff()
+	backoffConfig.InitialInterval = s.InitialInterval
+	backoffConfig.MaxInterval = s.MaxInterval
+	backoffConfig.MaxElapsedTime = 0
+	backoffConfig.Reset()
+
+	return backoffConfig
+}
+
+// consumeWithRetries calls the callback again as long as it returns an error and there are attempts left.
+// A message which was not acknowledged without an error is not retried. It returns the number of attempts made.
+// If the consumer is stopped while waiting for the next attempt, the message is given back to the input.
+func (c *Consumer) consumeWithRetries(ctx context.Context, msg *Message) (bool, int, error) {
+	retryBackOff := c.settings.Retry.newBackOff()
+
+	for attempt := 1; ; attempt++ {
+		ack, err := c.consumeWithTimeout(ctx, msg)
+
+		if err == nil || err == errConsumeTimeout || attempt >= c.settings.Retry.Attempts {
+			return ack, attempt, err
+		}
+
+		wait := retryBackOff.NextBackOff()
+
+		c.logger.WithContext(ctx).WithFields(mon.Fields{
+			"attempt": attempt,
+			"wait":    wait.String(),
+		}).Warnf("retrying the consume operation after the error: %s", err.Error())
+
+		c.mw.WriteOne(&mon.MetricDatum{
+			MetricName: metricNameConsumerRetryCount,
+			Value:      1.0,
+		})
+
+		select {
+		case <-ctx.Done():
+			return ack, attempt, err
+		case <-c.cfn.Dying():
+			c.logger.WithContext(ctx).Warnf("not retrying the consume operation as the consumer is stopping")
+			return false, attempt, errConsumeStopped
+		case <-time.After(wait):
+		}
+	}
+}
+
+// writeDeadLetter writes a copy of the message together with the error details to the dead letter output.
+// It returns true if the original message can be acknowledged.
+func (c *Consumer) writeDeadLetter(ctx context.Context, msg *Message, attempts int, consumeErr error) bool {
+	attributes := make(map[string]interface{}, len(msg.Attributes)+3)
+
+	for key, value := range msg.Attributes {
+		attributes[key] = value
+	}
+
+	delete(attributes, AttributeSqsReceiptHandle)
+	delete(attributes, AttributeBlobBucket)
+	delete(attributes, AttributeBlobPrefix)
+	delete(attributes, AttributeBlobKey)
+	attributes[AttributeDeadLetterError] = consumeErr.Error()
+	attributes[AttributeDeadLetterAttempts] = attempts
+	attributes[AttributeDeadLetterConsumer] = c.name
+
+	deadLetter := &Message{
+		Trace:      msg.Trace,
+		Attributes: attributes,
+		Body:       msg.Body,
+	}
+
+	err := c.deadLetter.WriteOne(ctx, deadLetter)
+
+	if err != nil {
+		c.logger.WithContext(ctx).Error(err, "could not write the message to the dead letter output")
+		return false
+	}
+
+	c.mw.WriteOne(&mon.MetricDatum{
+		MetricName: metricNameConsumerDeadLetterCount,
+		Value:      1.0,
+	})
+
+	return true
+}
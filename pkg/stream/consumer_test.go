@@ -0,0 +1,249 @@
+package stream_test
+
+import (
+	"context"
+	"errors"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/mon"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	streamMocks "github.com/applike/gosoline/pkg/stream/mocks"
+	"github.com/applike/gosoline/pkg/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"sync"
+	"testing"
+	"time"
+)
+
+func runConsumer(t *testing.T, callback stream.ConsumerCallback, deadLetter stream.Output, settings *stream.ConsumerSettings, messages ...*stream.Message) *stream.InMemoryInput {
+	logger := monMocks.NewLoggerMockedAll()
+	metric := monMocks.NewMetricWriterMockedAll()
+	input := stream.NewInMemoryInput(&stream.InMemorySettings{})
+
+	if settings.IdleTimeout == 0 {
+		settings.IdleTimeout = time.Hour
+	}
+
+	consumer := stream.NewConsumer(callback)
+	err := consumer.BootWithInterfaces(logger, tracing.NewNoopTracer(), metric, input, deadLetter, settings)
+	assert.NoError(t, err, "the consumer should boot without an error")
+
+	input.Publish(messages...)
+	go input.Stop()
+
+	err = consumer.Run(context.Background())
+	assert.NoError(t, err, "the consumer should run without an error")
+
+	return input
+}
+
+func TestConsumer_Run(t *testing.T) {
+	msg := &stream.Message{
+		Body: "foobar",
+	}
+
+	callback := new(streamMocks.ConsumerCallback)
+	callback.On("Consume", mock.Anything, msg).Return(true, nil).Once()
+
+	input := runConsumer(t, callback, nil, &stream.ConsumerSettings{}, msg)
+
+	callback.AssertExpectations(t)
+	assert.True(t, input.IsAcked(msg), "the message should be acknowledged")
+}
+
+func TestConsumer_RunRetry(t *testing.T) {
+	msg := &stream.Message{
+		Body: "foobar",
+	}
+
+	callback := new(streamMocks.ConsumerCallback)
+	callback.On("Consume", mock.Anything, msg).Return(false, errors.New("failure")).Twice()
+	callback.On("Consume", mock.Anything, msg).Return(true, nil).Once()
+
+	input := runConsumer(t, callback, nil, &stream.ConsumerSettings{
+		Retry: stream.ConsumerRetrySettings{
+			Attempts:        3,
+			InitialInterval: time.Millisecond,
+			MaxInterval:     time.Millisecond,
+		},
+	}, msg)
+
+	callback.AssertExpectations(t)
+	assert.True(t, input.IsAcked(msg), "the message should be acknowledged")
+}
+
+func TestConsumer_RunDeadLetter(t *testing.T) {
+	msg := &stream.Message{
+		Attributes: map[string]interface{}{
+			"foo": "bar",
+		},
+		Body: "foobar",
+	}
+
+	callback := new(streamMocks.ConsumerCallback)
+	callback.On("Consume", mock.Anything, msg).Return(false, errors.New("failure")).Twice()
+
+	deadLetter := stream.NewOutputMemory()
+	input := runConsumer(t, callback, deadLetter, &stream.ConsumerSettings{
+		Retry: stream.ConsumerRetrySettings{
+			Attempts:        2,
+			InitialInterval: time.Millisecond,
+			MaxInterval:     time.Millisecond,
+		},
+	}, msg)
+
+	callback.AssertExpectations(t)
+	assert.True(t, input.IsAcked(msg), "the message should be acknowledged after writing it to the dead letter output")
+
+	assert.Equal(t, 1, deadLetter.Size())
+	deadLetterMsg := deadLetter.Messages()[0]
+	assert.Equal(t, "foobar", deadLetterMsg.Body)
+	assert.Equal(t, "bar", deadLetterMsg.Attributes["foo"])
+	assert.Equal(t, "failure", deadLetterMsg.Attributes[stream.AttributeDeadLetterError])
+	assert.Equal(t, 2, deadLetterMsg.Attributes[stream.AttributeDeadLetterAttempts])
+}
+
+func TestConsumer_RunTimeout(t *testing.T) {
+	msg := &stream.Message{
+		Body: "foobar",
+	}
+
+	callback := new(streamMocks.ConsumerCallback)
+	callback.On("Consume", mock.Anything, msg).Run(func(args mock.Arguments) {
+		ctx := args.Get(0).(context.Context)
+		<-ctx.Done()
+	}).Return(true, nil).Once()
+
+	deadLetter := stream.NewOutputMemory()
+	input := runConsumer(t, callback, deadLetter, &stream.ConsumerSettings{
+		RunnerCount:    2,
+		ConsumeTimeout: 10 * time.Millisecond,
+	}, msg)
+
+	callback.AssertExpectations(t)
+	assert.False(t, input.IsAcked(msg), "the message should not be acknowledged after a timeout")
+	assert.Equal(t, []*stream.Message{msg}, input.Nacks(), "a timed out message should be returned to the input")
+	assert.Equal(t, 0, deadLetter.Size(), "a timed out message should not be written to the dead letter output")
+}
+
+func TestConsumer_RunStopWhileRetrying(t *testing.T) {
+	msg := &stream.Message{
+		Body: "foobar",
+	}
+
+	consumed := make(chan struct{})
+	callback := new(streamMocks.ConsumerCallback)
+	callback.On("Consume", mock.Anything, msg).Run(func(args mock.Arguments) {
+		close(consumed)
+	}).Return(false, errors.New("failure")).Once()
+
+	logger := monMocks.NewLoggerMockedAll()
+	metric := monMocks.NewMetricWriterMockedAll()
+	input := stream.NewInMemoryInput(&stream.InMemorySettings{})
+	deadLetter := stream.NewOutputMemory()
+
+	consumer := stream.NewConsumer(callback)
+	err := consumer.BootWithInterfaces(logger, tracing.NewNoopTracer(), metric, input, deadLetter, &stream.ConsumerSettings{
+		RunnerCount: 1,
+		IdleTimeout: time.Hour,
+		Retry: stream.ConsumerRetrySettings{
+			Attempts:        3,
+			InitialInterval: time.Hour,
+			MaxInterval:     time.Hour,
+		},
+	})
+	assert.NoError(t, err, "the consumer should boot without an error")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	input.Publish(msg)
+
+	go func() {
+		<-consumed
+		cancel()
+	}()
+
+	done := make(chan error)
+	go func() {
+		done <- consumer.Run(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err, "the consumer should run without an error")
+	case <-time.After(time.Second):
+		assert.FailNow(t, "the consumer should not wait for the next retry after it was stopped")
+	}
+
+	callback.AssertExpectations(t)
+	assert.False(t, input.IsAcked(msg), "the message should not be acknowledged")
+	assert.Equal(t, []*stream.Message{msg}, input.Nacks(), "the message should be returned to the input")
+	assert.Equal(t, 0, deadLetter.Size(), "the message should not be written to the dead letter output")
+}
+
+func TestConsumer_RunNack(t *testing.T) {
+	msg := &stream.Message{
+		Body: "foobar",
+	}
+
+	callback := new(streamMocks.ConsumerCallback)
+	callback.On("Consume", mock.Anything, msg).Return(false, nil).Once()
+
+	input := runConsumer(t, callback, nil, &stream.ConsumerSettings{}, msg)
+
+	callback.AssertExpectations(t)
+	assert.False(t, input.IsAcked(msg))
+	assert.Equal(t, []*stream.Message{msg}, input.Nacks(), "a message which was not acknowledged should be returned to the input")
+}
+
+type orderRecordingCallback struct {
+	lck   sync.Mutex
+	order map[string][]string
+}
+
+func (c *orderRecordingCallback) Boot(_ cfg.Config, _ mon.Logger) error {
+	return nil
+}
+
+func (c *orderRecordingCallback) Consume(_ context.Context, msg *stream.Message) (bool, error) {
+	key := msg.Attributes[stream.AttributeSqsMessageGroupId].(string)
+	time.Sleep(time.Duration(len(msg.Body)) * time.Millisecond)
+
+	c.lck.Lock()
+	defer c.lck.Unlock()
+
+	c.order[key] = append(c.order[key], msg.Body)
+
+	return true, nil
+}
+
+func TestConsumer_RunPartitioned(t *testing.T) {
+	messages := make([]*stream.Message, 0)
+	expected := map[string][]string{
+		"a": {"aaaa", "a", "aaa"},
+		"b": {"bb", "bbbbb", "b"},
+	}
+
+	for i := 0; i < 3; i++ {
+		for _, key := range []string{"a", "b"} {
+			messages = append(messages, &stream.Message{
+				Attributes: map[string]interface{}{
+					stream.AttributeSqsMessageGroupId: key,
+				},
+				Body: expected[key][i],
+			})
+		}
+	}
+
+	callback := &orderRecordingCallback{
+		order: make(map[string][]string),
+	}
+
+	input := runConsumer(t, callback, nil, &stream.ConsumerSettings{
+		RunnerCount: 4,
+		Partitioned: true,
+	}, messages...)
+
+	assert.Equal(t, expected, callback.order)
+	assert.Len(t, input.Acks(), 6)
+}
@@ -0,0 +1,127 @@
+package stream
+
+import (
+	"context"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/pkg/errors"
+)
+
+const (
+	AttributeDecodeError               = "decodeError"
+	metricNameConsumerDecodeErrorCount = "ConsumerDecodeErrorCount"
+)
+
+//go:generate mockery -name=TypedConsumerCallback
+type TypedConsumerCallback interface {
+	Boot(config cfg.Config, logger mon.Logger) error
+	// GetModel returns a new pointer the body of the next message gets decoded into
+	GetModel() interface{}
+	Consume(ctx context.Context, model interface{}, attributes map[string]interface{}) (bool, error)
+}
+
+type typedConsumerCallback struct {
+	logger      mon.Logger
+	mw          mon.MetricWriter
+	callback    TypedConsumerCallback
+	errorOutput Output
+}
+
+// NewTypedConsumer creates a consumer which decodes the body of every message according to its encoding
+// before it is handed to the callback
+func NewTypedConsumer(callback TypedConsumerCallback) *Consumer {
+	return NewConsumer(NewTypedConsumerCallback(callback))
+}
+
+func NewTypedConsumerCallback(callback TypedConsumerCallback) ConsumerCallback {
+	return &typedConsumerCallback{
+		callback: callback,
+	}
+}
+
+// NewTypedConsumerCallbackWithInterfaces creates the callback without booting it. The error output is optional.
+func NewTypedConsumerCallbackWithInterfaces(logger mon.Logger, mw mon.MetricWriter, callback TypedConsumerCallback, errorOutput Output) ConsumerCallback {
+	return &typedConsumerCallback{
+		logger:      logger,
+		mw:          mw,
+		callback:    callback,
+		errorOutput: errorOutput,
+	}
+}
+
+func (c *typedConsumerCallback) Boot(config cfg.Config, logger mon.Logger) error {
+	err := c.callback.Boot(config, logger)
+
+	if err != nil {
+		return err
+	}
+
+	c.logger = logger
+	c.mw = mon.NewMetricDaemonWriter(getTypedConsumerDefaultMetrics()...)
+
+	if config.IsSet("consumer_decode_error_output") {
+		c.errorOutput = NewConfigurableOutput(config, logger, config.GetString("consumer_decode_error_output"))
+	}
+
+	return nil
+}
+
+func (c *typedConsumerCallback) Consume(ctx context.Context, msg *Message) (bool, error) {
+	model := c.callback.GetModel()
+	err := msg.DecodeBody(model)
+
+	if err != nil {
+		return c.handleDecodeError(ctx, msg, err)
+	}
+
+	return c.callback.Consume(ctx, model, msg.Attributes)
+}
+
+// handleDecodeError writes an undecodable message to the error output. Without an error output the error is
+// returned, so the retry and dead letter settings of the consumer apply.
+func (c *typedConsumerCallback) handleDecodeError(ctx context.Context, msg *Message, decodeErr error) (bool, error) {
+	decodeErr = errors.Wrap(decodeErr, "can not decode the body of the message")
+
+	c.mw.WriteOne(&mon.MetricDatum{
+		MetricName: metricNameConsumerDecodeErrorCount,
+		Value:      1.0,
+	})
+
+	if c.errorOutput == nil {
+		return false, decodeErr
+	}
+
+	c.logger.WithContext(ctx).Warnf("writing the message to the decode error output: %s", decodeErr.Error())
+
+	attributes := make(map[string]interface{}, len(msg.Attributes)+1)
+
+	for key, value := range msg.Attributes {
+		attributes[key] = value
+	}
+
+	delete(attributes, AttributeSqsReceiptHandle)
+	attributes[AttributeDecodeError] = decodeErr.Error()
+
+	err := c.errorOutput.WriteOne(ctx, &Message{
+		Trace:      msg.Trace,
+		Attributes: attributes,
+		Body:       msg.Body,
+	})
+
+	if err != nil {
+		return false, errors.Wrap(err, "could not write the message to the decode error output")
+	}
+
+	return true, nil
+}
+
+func getTypedConsumerDefaultMetrics() mon.MetricData {
+	return mon.MetricData{
+		{
+			Priority:   mon.PriorityHigh,
+			MetricName: metricNameConsumerDecodeErrorCount,
+			Unit:       mon.UnitCount,
+			Value:      0.0,
+		},
+	}
+}
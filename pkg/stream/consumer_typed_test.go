@@ -0,0 +1,138 @@
+package stream_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/mon"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	streamMocks "github.com/applike/gosoline/pkg/stream/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"sync"
+	"testing"
+	"time"
+)
+
+type typedConsumerModel struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestTypedConsumer_Run(t *testing.T) {
+	msg := &stream.Message{
+		Attributes: map[string]interface{}{
+			"foo": "bar",
+		},
+		Body: `{"id":1,"name":"foo"}`,
+	}
+
+	typed := new(streamMocks.TypedConsumerCallback)
+	typed.On("GetModel").Return(func() interface{} {
+		return &typedConsumerModel{}
+	}).Once()
+	typed.On("Consume", mock.Anything, &typedConsumerModel{Id: 1, Name: "foo"}, msg.Attributes).Return(true, nil).Once()
+
+	logger := monMocks.NewLoggerMockedAll()
+	metric := monMocks.NewMetricWriterMockedAll()
+	callback := stream.NewTypedConsumerCallbackWithInterfaces(logger, metric, typed, nil)
+
+	input := runConsumer(t, callback, nil, &stream.ConsumerSettings{}, msg)
+
+	typed.AssertExpectations(t)
+	assert.True(t, input.IsAcked(msg), "the message should be acknowledged")
+}
+
+func TestTypedConsumer_RunDecodeError(t *testing.T) {
+	msg := &stream.Message{
+		Body: `{"id":"no int"}`,
+	}
+
+	typed := new(streamMocks.TypedConsumerCallback)
+	typed.On("GetModel").Return(func() interface{} {
+		return &typedConsumerModel{}
+	}).Once()
+
+	logger := monMocks.NewLoggerMockedAll()
+	metric := monMocks.NewMetricWriterMockedAll()
+	errorOutput := stream.NewOutputMemory()
+	callback := stream.NewTypedConsumerCallbackWithInterfaces(logger, metric, typed, errorOutput)
+
+	input := runConsumer(t, callback, nil, &stream.ConsumerSettings{}, msg)
+
+	typed.AssertExpectations(t)
+	typed.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything, mock.Anything)
+	assert.True(t, input.IsAcked(msg), "the message should be acknowledged after writing it to the error output")
+
+	assert.Equal(t, 1, errorOutput.Size())
+	assert.Contains(t, errorOutput.Messages()[0].Attributes, stream.AttributeDecodeError)
+	metric.AssertCalled(t, "WriteOne", mock.MatchedBy(func(datum *mon.MetricDatum) bool {
+		return datum.MetricName == "ConsumerDecodeErrorCount"
+	}))
+}
+
+type partitionedTypedCallback struct {
+	lck   sync.Mutex
+	order map[string][]string
+}
+
+func (c *partitionedTypedCallback) Boot(_ cfg.Config, _ mon.Logger) error {
+	return nil
+}
+
+func (c *partitionedTypedCallback) GetModel() interface{} {
+	return &typedConsumerModel{}
+}
+
+func (c *partitionedTypedCallback) GetPartitionKey(msg *stream.Message) string {
+	return msg.Attributes["key"].(string)
+}
+
+func (c *partitionedTypedCallback) Consume(_ context.Context, model interface{}, attributes map[string]interface{}) (bool, error) {
+	name := model.(*typedConsumerModel).Name
+	key := attributes["key"].(string)
+	time.Sleep(time.Duration(len(name)) * time.Millisecond)
+
+	c.lck.Lock()
+	defer c.lck.Unlock()
+
+	c.order[key] = append(c.order[key], name)
+
+	return true, nil
+}
+
+func TestTypedConsumer_RunPartitioned(t *testing.T) {
+	messages := make([]*stream.Message, 0)
+	expected := map[string][]string{
+		"a": {"aaaa", "a", "aaa"},
+		"b": {"bb", "bbbbb", "b"},
+	}
+
+	for i := 0; i < 3; i++ {
+		for _, key := range []string{"a", "b"} {
+			messages = append(messages, &stream.Message{
+				Attributes: map[string]interface{}{
+					"key": key,
+				},
+				Body: fmt.Sprintf(`{"id":%d,"name":"%s"}`, i, expected[key][i]),
+			})
+		}
+	}
+
+	typed := &partitionedTypedCallback{
+		order: make(map[string][]string),
+	}
+
+	logger := monMocks.NewLoggerMockedAll()
+	metric := monMocks.NewMetricWriterMockedAll()
+	callback := stream.NewTypedConsumerCallbackWithInterfaces(logger, metric, typed, nil)
+
+	input := runConsumer(t, callback, nil, &stream.ConsumerSettings{
+		RunnerCount: 4,
+		Partitioned: true,
+	}, messages...)
+
+	assert.Equal(t, expected, typed.order, "the partition key of the typed callback should be used")
+	assert.Len(t, input.Acks(), 6)
+}
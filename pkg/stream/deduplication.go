@@ -0,0 +1,146 @@
+package stream
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/kvstore"
+	"github.com/applike/gosoline/pkg/mon"
+	"time"
+)
+
+const (
+	AttributeSqsMessageId = "sqsMessageId"
+	AttributeSnsMessageId = "snsMessageId"
+)
+
+const (
+	MiddlewareDeduplication          = "deduplication"
+	metricNameConsumerDuplicateCount = "ConsumerDuplicateCount"
+	defaultDeduplicationTtl          = 24 * time.Hour
+)
+
+type DeduplicationSettings struct {
+	// Enabled is used by subscriptions, consumers enable the deduplication by the middleware of the same name
+	Enabled bool `cfg:"enabled"`
+	// Name of the kvstore remembering the processed message ids, defaults to the name of the input
+	Name string `cfg:"name"`
+	// Ttl in seconds, defaults to a day
+	Ttl int `cfg:"ttl"`
+	// UseBodyHash identifies messages by the hash of their body instead of the id assigned by sqs or sns
+	UseBodyHash bool `cfg:"use_body_hash"`
+}
+
+// Deduplicator remembers the ids of processed messages for the configured ttl. Messages which are delivered
+// concurrently can still be processed twice, as the id is only stored after a message was processed.
+type Deduplicator struct {
+	store    kvstore.KvStore
+	settings DeduplicationSettings
+}
+
+func NewDeduplicator(config cfg.Config, logger mon.Logger, settings DeduplicationSettings) *Deduplicator {
+	ttl := time.Duration(settings.Ttl) * time.Second
+
+	if ttl <= 0 {
+		ttl = defaultDeduplicationTtl
+	}
+
+	store := kvstore.NewChainKvStore(config, logger, &kvstore.Settings{
+		Name: fmt.Sprintf("dedup-%s", settings.Name),
+		Ttl:  ttl,
+	})
+	store.Add(kvstore.NewRedisKvStore)
+
+	return NewDeduplicatorWithInterfaces(store, settings)
+}
+
+func NewDeduplicatorWithInterfaces(store kvstore.KvStore, settings DeduplicationSettings) *Deduplicator {
+	return &Deduplicator{
+		store:    store,
+		settings: settings,
+	}
+}
+
+// GetMessageId prefers the id of sns over the one of sqs, as it is the same for all queues subscribed to a topic.
+// Messages without any of them are identified by the hash of their body.
+func (d *Deduplicator) GetMessageId(msg *Message) string {
+	if !d.settings.UseBodyHash {
+		for _, attribute := range []string{AttributeSnsMessageId, AttributeSqsMessageId} {
+			if id, ok := msg.Attributes[attribute].(string); ok && id != "" {
+				return id
+			}
+		}
+	}
+
+	hash := sha256.Sum256([]byte(msg.Body))
+
+	return hex.EncodeToString(hash[:])
+}
+
+func (d *Deduplicator) IsDuplicate(ctx context.Context, msg *Message) (bool, error) {
+	return d.store.Contains(ctx, d.GetMessageId(msg))
+}
+
+func (d *Deduplicator) MarkProcessed(ctx context.Context, msg *Message) error {
+	return d.store.Put(ctx, d.GetMessageId(msg), true)
+}
+
+// NewDeduplicationMiddleware skips and acknowledges messages which were already processed. If the kvstore is
+// not available, the message is processed anyway.
+func NewDeduplicationMiddleware(logger mon.Logger, mw mon.MetricWriter, deduplicator *Deduplicator) ConsumerMiddleware {
+	return func(next ConsumeHandler) ConsumeHandler {
+		return func(ctx context.Context, msg *Message) (bool, error) {
+			duplicate, err := deduplicator.IsDuplicate(ctx, msg)
+
+			if err != nil {
+				logger.WithContext(ctx).Error(err, "could not check if the message is a duplicate")
+			}
+
+			if duplicate {
+				logger.WithContext(ctx).Infof("skipping the duplicate message %s", deduplicator.GetMessageId(msg))
+				mw.WriteOne(&mon.MetricDatum{
+					MetricName: metricNameConsumerDuplicateCount,
+					Value:      1.0,
+				})
+
+				return true, nil
+			}
+
+			ack, err := next(ctx, msg)
+
+			if !ack || err != nil {
+				return ack, err
+			}
+
+			if err := deduplicator.MarkProcessed(ctx, msg); err != nil {
+				logger.WithContext(ctx).Error(err, "could not remember the message as processed")
+			}
+
+			return ack, nil
+		}
+	}
+}
+
+func newDeduplicationMiddlewareFromConfig(config cfg.Config, logger mon.Logger, input string) ConsumerMiddleware {
+	key := fmt.Sprintf("input_%s_deduplication", input)
+	settings := DeduplicationSettings{}
+
+	if config.IsSet(key) {
+		config.UnmarshalKey(key, &settings)
+	}
+
+	if settings.Name == "" {
+		settings.Name = input
+	}
+
+	mw := mon.NewMetricDaemonWriter(&mon.MetricDatum{
+		Priority:   mon.PriorityHigh,
+		MetricName: metricNameConsumerDuplicateCount,
+		Unit:       mon.UnitCount,
+		Value:      0.0,
+	})
+
+	return NewDeduplicationMiddleware(logger, mw, NewDeduplicator(config, logger, settings))
+}
@@ -0,0 +1,74 @@
+package stream_test
+
+import (
+	"context"
+	kvstoreMocks "github.com/applike/gosoline/pkg/kvstore/mocks"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+func TestDeduplicator_GetMessageId(t *testing.T) {
+	deduplicator := stream.NewDeduplicatorWithInterfaces(nil, stream.DeduplicationSettings{})
+
+	assert.Equal(t, "sns", deduplicator.GetMessageId(&stream.Message{
+		Attributes: map[string]interface{}{
+			stream.AttributeSnsMessageId: "sns",
+			stream.AttributeSqsMessageId: "sqs",
+		},
+	}))
+	assert.Equal(t, "sqs", deduplicator.GetMessageId(&stream.Message{
+		Attributes: map[string]interface{}{
+			stream.AttributeSqsMessageId: "sqs",
+		},
+	}))
+
+	first := deduplicator.GetMessageId(&stream.Message{Body: "foobar"})
+	second := deduplicator.GetMessageId(&stream.Message{Body: "foobar"})
+	other := deduplicator.GetMessageId(&stream.Message{Body: "barfoo"})
+
+	assert.Equal(t, first, second, "equal bodies should have the same id")
+	assert.NotEqual(t, first, other, "different bodies should have different ids")
+}
+
+func TestDeduplicationMiddleware(t *testing.T) {
+	duplicate := &stream.Message{
+		Attributes: map[string]interface{}{
+			stream.AttributeSqsMessageId: "duplicate",
+		},
+	}
+	fresh := &stream.Message{
+		Attributes: map[string]interface{}{
+			stream.AttributeSqsMessageId: "fresh",
+		},
+	}
+
+	store := new(kvstoreMocks.KvStore)
+	store.On("Contains", mock.Anything, "duplicate").Return(true, nil).Once()
+	store.On("Contains", mock.Anything, "fresh").Return(false, nil).Once()
+	store.On("Put", mock.Anything, "fresh", true).Return(nil).Once()
+
+	logger := monMocks.NewLoggerMockedAll()
+	metric := monMocks.NewMetricWriterMockedAll()
+	deduplicator := stream.NewDeduplicatorWithInterfaces(store, stream.DeduplicationSettings{})
+
+	consumed := make([]*stream.Message, 0)
+	handler := stream.NewDeduplicationMiddleware(logger, metric, deduplicator)(func(ctx context.Context, msg *stream.Message) (bool, error) {
+		consumed = append(consumed, msg)
+		return true, nil
+	})
+
+	ack, err := handler(context.Background(), duplicate)
+	assert.NoError(t, err)
+	assert.True(t, ack, "a duplicate should be acknowledged")
+
+	ack, err = handler(context.Background(), fresh)
+	assert.NoError(t, err)
+	assert.True(t, ack)
+
+	assert.Equal(t, []*stream.Message{fresh}, consumed, "only the fresh message should be consumed")
+	store.AssertExpectations(t)
+	metric.AssertNumberOfCalls(t, "WriteOne", 1)
+}
@@ -1,5 +1,10 @@
 package stream
 
+import "time"
+
+// NackDefaultDelay returns a message after the default delay of the input, e.g. the visibility timeout of a sqs queue
+const NackDefaultDelay time.Duration = -1
+
 //go:generate mockery -name Input
 type Input interface {
 	Run() error
@@ -10,4 +15,15 @@ type Input interface {
 //go:generate mockery -name AcknowledgeableInput
 type AcknowledgeableInput interface {
 	Ack(msg *Message) error
+	// Nack returns the message to the input, so it gets delivered again after the delay
+	Nack(msg *Message, delay time.Duration) error
+}
+
+//go:generate mockery -name BatchAcknowledgeableInput
+
+// BatchAcknowledgeableInput can acknowledge several messages at once. Inputs which collect single acknowledgements
+// to write them in batches write the collected ones on FlushAcks.
+type BatchAcknowledgeableInput interface {
+	AckBatch(msgs []*Message) error
+	FlushAcks() error
 }
@@ -5,37 +5,62 @@ import (
 	"github.com/applike/gosoline/pkg/cfg"
 	"github.com/applike/gosoline/pkg/mon"
 	"github.com/applike/gosoline/pkg/sqs"
+	"sync"
 	"time"
 )
 
 const (
-	InputTypeFile    = "file"
-	InputTypeKinesis = "kinesis"
-	InputTypeRedis   = "redis"
-	InputTypeSns     = "sns"
-	InputTypeSqs     = "sqs"
+	InputTypeFile        = "file"
+	InputTypeInMemory    = "inMemory"
+	InputTypeKinesis     = "kinesis"
+	InputTypeRedis       = "redis"
+	InputTypeRedisStream = "redisStream"
+	InputTypeSns         = "sns"
+	InputTypeSqs         = "sqs"
 )
 
+type InputFactory func(config cfg.Config, logger mon.Logger, name string) Input
+
+var inputFactories = struct {
+	sync.Mutex
+	factories map[string]InputFactory
+}{
+	factories: make(map[string]InputFactory),
+}
+
+func init() {
+	RegisterInputFactory(InputTypeFile, newFileInputFromConfig)
+	RegisterInputFactory(InputTypeInMemory, newInMemoryInputFromConfig)
+	RegisterInputFactory(InputTypeKinesis, newKinesisInputFromConfig)
+	RegisterInputFactory(InputTypeRedis, newRedisInputFromConfig)
+	RegisterInputFactory(InputTypeRedisStream, newRedisStreamInputFromConfig)
+	RegisterInputFactory(InputTypeSns, newSnsInputFromConfig)
+	RegisterInputFactory(InputTypeSqs, newSqsInputFromConfig)
+}
+
+// RegisterInputFactory makes an input type available for config driven inputs via input_<name>_type.
+// Registering a factory for an already known type replaces the existing one.
+func RegisterInputFactory(typ string, factory InputFactory) {
+	inputFactories.Lock()
+	defer inputFactories.Unlock()
+
+	inputFactories.factories[typ] = factory
+}
+
 func NewConfigurableInput(config cfg.Config, logger mon.Logger, name string) Input {
 	key := fmt.Sprintf("input_%s_type", name)
 	t := config.GetString(key)
 
-	switch t {
-	case InputTypeFile:
-		return newFileInputFromConfig(config, logger, name)
-	case InputTypeKinesis:
-		return newKinesisInputFromConfig(config, logger, name)
-	case InputTypeRedis:
-		return newRedisInputFromConfig(config, logger, name)
-	case InputTypeSns:
-		return newSnsInputFromConfig(config, logger, name)
-	case InputTypeSqs:
-		return newSqsInputFromConfig(config, logger, name)
-	default:
+	inputFactories.Lock()
+	factory, ok := inputFactories.factories[t]
+	inputFactories.Unlock()
+
+	if !ok {
 		logger.Fatalf(fmt.Errorf("invalid input %s of type %s", name, t), "invalid input %s of type %s", name, t)
+		return nil
 	}
 
-	return nil
+	return factory(config, logger, name)
 }
 
 func newFileInputFromConfig(config cfg.Config, logger mon.Logger, name string) Input {
@@ -46,6 +71,17 @@ func newFileInputFromConfig(config cfg.Config, logger mon.Logger, name string) I
 	return NewFileInput(config, logger, settings)
 }
 
+func newInMemoryInputFromConfig(config cfg.Config, _ mon.Logger, name string) Input {
+	key := getConfigurableInputKey(name)
+	settings := &InMemorySettings{}
+
+	if config.IsSet(key) {
+		config.UnmarshalKey(key, settings)
+	}
+
+	return ProvideInMemoryInput(name, settings)
+}
+
 type kinesisInputConfiguration struct {
 	StreamName      string `cfg:"streamName"`
 	ApplicationName string `cfg:"applicationName"`
@@ -94,6 +130,47 @@ func newRedisInputFromConfig(config cfg.Config, logger mon.Logger, name string)
 	return NewRedisListInput(config, logger, settings)
 }
 
+type redisStreamInputConfiguration struct {
+	Project       string        `cfg:"project"`
+	Family        string        `cfg:"family"`
+	Application   string        `cfg:"application"`
+	ServerName    string        `cfg:"serverName"`
+	Key           string        `cfg:"key"`
+	Group         string        `cfg:"group"`
+	Consumer      string        `cfg:"consumer"`
+	BatchSize     int64         `cfg:"batchSize"`
+	BlockTime     time.Duration `cfg:"blockTime"`
+	ClaimInterval time.Duration `cfg:"claimInterval"`
+	ClaimMinIdle  time.Duration `cfg:"claimMinIdle"`
+	MaxDeliveries int64         `cfg:"maxDeliveries"`
+}
+
+func newRedisStreamInputFromConfig(config cfg.Config, logger mon.Logger, name string) Input {
+	key := getConfigurableInputKey(name)
+
+	configuration := redisStreamInputConfiguration{}
+	config.UnmarshalKey(key, &configuration)
+
+	settings := &RedisStreamInputSettings{
+		AppId: cfg.AppId{
+			Project:     configuration.Project,
+			Family:      configuration.Family,
+			Application: configuration.Application,
+		},
+		ServerName:    configuration.ServerName,
+		Key:           configuration.Key,
+		Group:         configuration.Group,
+		Consumer:      configuration.Consumer,
+		BatchSize:     configuration.BatchSize,
+		BlockTime:     configuration.BlockTime * time.Second,
+		ClaimInterval: configuration.ClaimInterval * time.Second,
+		ClaimMinIdle:  configuration.ClaimMinIdle * time.Second,
+		MaxDeliveries: configuration.MaxDeliveries,
+	}
+
+	return NewRedisStreamInput(config, logger, settings)
+}
+
 type snsInputTarget struct {
 	Family      string `cfg:"family"`
 	Application string `cfg:"application"`
@@ -101,12 +178,15 @@ type snsInputTarget struct {
 }
 
 type snsInputConfiguration struct {
-	ConsumerId        string            `cfg:"id"`
-	WaitTime          int64             `cfg:"wait_time"`
-	VisibilityTimeout int               `cfg:"visibility_timeout"`
-	RedrivePolicy     sqs.RedrivePolicy `cfg:"redrive_policy"`
-	RunnerCount       int               `cfg:"runner_count"`
-	Targets           []snsInputTarget  `cfg:"targets"`
+	ConsumerId          string                         `cfg:"id"`
+	WaitTime            int64                          `cfg:"wait_time"`
+	VisibilityTimeout   int                            `cfg:"visibility_timeout"`
+	RedrivePolicy       sqs.RedrivePolicy              `cfg:"redrive_policy"`
+	RunnerCount         int                            `cfg:"runner_count"`
+	Offload             OffloadSettings                `cfg:"offload"`
+	VisibilityHeartbeat SqsVisibilityHeartbeatSettings `cfg:"visibility_heartbeat"`
+	AckBatch            SqsAckBatchSettings            `cfg:"ack_batch"`
+	Targets             []snsInputTarget               `cfg:"targets"`
 }
 
 func newSnsInputFromConfig(config cfg.Config, logger mon.Logger, name string) Input {
@@ -116,11 +196,14 @@ func newSnsInputFromConfig(config cfg.Config, logger mon.Logger, name string) In
 	config.UnmarshalKey(key, &configuration)
 
 	settings := SnsInputSettings{
-		QueueId:           configuration.ConsumerId,
-		WaitTime:          configuration.WaitTime,
-		RedrivePolicy:     configuration.RedrivePolicy,
-		VisibilityTimeout: configuration.VisibilityTimeout,
-		RunnerCount:       configuration.RunnerCount,
+		QueueId:             configuration.ConsumerId,
+		WaitTime:            configuration.WaitTime,
+		RedrivePolicy:       configuration.RedrivePolicy,
+		VisibilityTimeout:   configuration.VisibilityTimeout,
+		RunnerCount:         configuration.RunnerCount,
+		Offload:             configuration.Offload,
+		VisibilityHeartbeat: configuration.VisibilityHeartbeat,
+		AckBatch:            configuration.AckBatch,
 	}
 
 	targets := make([]SnsInputTarget, len(configuration.Targets))
@@ -138,14 +221,17 @@ func newSnsInputFromConfig(config cfg.Config, logger mon.Logger, name string) In
 }
 
 type sqsInputConfiguration struct {
-	Family            string            `cfg:"target_family"`
-	Application       string            `cfg:"target_application"`
-	QueueId           string            `cfg:"target_queue_id"`
-	Fifo              sqs.FifoSettings  `cfg:"fifo"`
-	WaitTime          int64             `cfg:"wait_time"`
-	VisibilityTimeout int               `cfg:"visibility_timeout"`
-	RedrivePolicy     sqs.RedrivePolicy `cfg:"redrive_policy"`
-	RunnerCount       int               `cfg:"runner_count"`
+	Family              string                         `cfg:"target_family"`
+	Application         string                         `cfg:"target_application"`
+	QueueId             string                         `cfg:"target_queue_id"`
+	Fifo                sqs.FifoSettings               `cfg:"fifo"`
+	WaitTime            int64                          `cfg:"wait_time"`
+	VisibilityTimeout   int                            `cfg:"visibility_timeout"`
+	RedrivePolicy       sqs.RedrivePolicy              `cfg:"redrive_policy"`
+	RunnerCount         int                            `cfg:"runner_count"`
+	Offload             OffloadSettings                `cfg:"offload"`
+	VisibilityHeartbeat SqsVisibilityHeartbeatSettings `cfg:"visibility_heartbeat"`
+	AckBatch            SqsAckBatchSettings            `cfg:"ack_batch"`
 }
 
 func newSqsInputFromConfig(config cfg.Config, logger mon.Logger, name string) Input {
@@ -159,12 +245,15 @@ func newSqsInputFromConfig(config cfg.Config, logger mon.Logger, name string) In
 			Family:      configuration.Family,
 			Application: configuration.Application,
 		},
-		QueueId:           configuration.QueueId,
-		Fifo:              configuration.Fifo,
-		WaitTime:          configuration.WaitTime,
-		VisibilityTimeout: configuration.VisibilityTimeout,
-		RedrivePolicy:     configuration.RedrivePolicy,
-		RunnerCount:       configuration.RunnerCount,
+		QueueId:             configuration.QueueId,
+		Fifo:                configuration.Fifo,
+		WaitTime:            configuration.WaitTime,
+		VisibilityTimeout:   configuration.VisibilityTimeout,
+		RedrivePolicy:       configuration.RedrivePolicy,
+		RunnerCount:         configuration.RunnerCount,
+		Offload:             configuration.Offload,
+		VisibilityHeartbeat: configuration.VisibilityHeartbeat,
+		AckBatch:            configuration.AckBatch,
 	}
 
 	return NewSqsInput(config, logger, settings)
@@ -0,0 +1,27 @@
+package stream_test
+
+import (
+	"github.com/applike/gosoline/pkg/cfg"
+	configMocks "github.com/applike/gosoline/pkg/cfg/mocks"
+	"github.com/applike/gosoline/pkg/mon"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	streamMocks "github.com/applike/gosoline/pkg/stream/mocks"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewConfigurableInput_CustomType(t *testing.T) {
+	config := new(configMocks.Config)
+	config.On("GetString", "input_custom_type").Return("inHouse")
+
+	logger := monMocks.NewLoggerMockedAll()
+	input := new(streamMocks.Input)
+
+	stream.RegisterInputFactory("inHouse", func(_ cfg.Config, _ mon.Logger, name string) stream.Input {
+		assert.Equal(t, "custom", name)
+		return input
+	})
+
+	assert.Equal(t, input, stream.NewConfigurableInput(config, logger, "custom"))
+}
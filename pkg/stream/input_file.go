@@ -2,15 +2,26 @@ package stream
 
 import (
 	"bufio"
+	"compress/gzip"
 	"encoding/json"
+	"fmt"
 	"github.com/applike/gosoline/pkg/cfg"
 	"github.com/applike/gosoline/pkg/mon"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 )
 
 type FileSettings struct {
+	// Filename is either a file, a directory or a glob pattern. The files of a directory or pattern are read in
+	// the order of their names, files ending with .gz are decompressed.
 	Filename string `cfg:"filename"`
 	Blocking bool
+	// ReplayRate limits the number of messages per second, all messages are read as fast as possible if not set
+	ReplayRate float64 `cfg:"replay_rate"`
 }
 
 type fileInput struct {
@@ -44,14 +55,100 @@ func (i *fileInput) Run() error {
 		}
 	}()
 
-	file, err := os.Open(i.settings.Filename)
+	filenames, err := i.getFilenames()
 
 	if err != nil {
-		i.logger.Error(err, "can not open file")
+		i.logger.Error(err, "can not find the files to read")
 		return err
 	}
 
-	scanner := bufio.NewScanner(file)
+	var rate <-chan time.Time
+
+	if i.settings.ReplayRate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / i.settings.ReplayRate))
+		defer ticker.Stop()
+
+		rate = ticker.C
+	}
+
+	for _, filename := range filenames {
+		if i.stopped {
+			break
+		}
+
+		if err = i.readFile(filename, rate); err != nil {
+			i.logger.Error(err, "can not read file")
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (i *fileInput) Stop() {
+	i.stopped = true
+}
+
+func (i *fileInput) getFilenames() ([]string, error) {
+	stat, err := os.Stat(i.settings.Filename)
+
+	if err == nil && !stat.IsDir() {
+		return []string{i.settings.Filename}, nil
+	}
+
+	pattern := i.settings.Filename
+
+	if err == nil && stat.IsDir() {
+		pattern = filepath.Join(pattern, "*")
+	}
+
+	matches, globErr := filepath.Glob(pattern)
+
+	if globErr != nil {
+		return nil, globErr
+	}
+
+	filenames := make([]string, 0, len(matches))
+
+	for _, match := range matches {
+		if stat, err := os.Stat(match); err == nil && !stat.IsDir() {
+			filenames = append(filenames, match)
+		}
+	}
+
+	if len(filenames) == 0 {
+		return nil, fmt.Errorf("there are no files matching %s", i.settings.Filename)
+	}
+
+	sort.Strings(filenames)
+
+	return filenames, nil
+}
+
+func (i *fileInput) readFile(filename string, rate <-chan time.Time) error {
+	file, err := os.Open(filename)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	var reader io.Reader = file
+
+	if strings.HasSuffix(filename, ".gz") {
+		gzipReader, err := gzip.NewReader(file)
+
+		if err != nil {
+			return err
+		}
+
+		defer gzipReader.Close()
+
+		reader = gzipReader
+	}
+
+	scanner := bufio.NewScanner(reader)
 	for scanner.Scan() {
 		if i.stopped {
 			break
@@ -67,12 +164,12 @@ func (i *fileInput) Run() error {
 			continue
 		}
 
+		if rate != nil {
+			<-rate
+		}
+
 		i.channel <- &msg
 	}
 
-	return nil
-}
-
-func (i *fileInput) Stop() {
-	i.stopped = true
+	return scanner.Err()
 }
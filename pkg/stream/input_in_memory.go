@@ -0,0 +1,170 @@
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+const defaultInMemoryInputSize = 1000
+
+type InMemorySettings struct {
+	Size int `cfg:"size"`
+}
+
+type InMemoryInput struct {
+	lck      sync.Mutex
+	channel  chan *Message
+	stop     chan struct{}
+	stopOnce sync.Once
+	closed   bool
+	ackLck   sync.Mutex
+	acks     []*Message
+	nacks    []*Message
+}
+
+var inMemoryInputs = struct {
+	sync.Mutex
+	instances map[string]*InMemoryInput
+}{
+	instances: make(map[string]*InMemoryInput),
+}
+
+// ProvideInMemoryInput returns the in memory input registered for the name and creates it on the first call.
+// The settings are only used when the input gets created.
+func ProvideInMemoryInput(name string, settings *InMemorySettings) *InMemoryInput {
+	inMemoryInputs.Lock()
+	defer inMemoryInputs.Unlock()
+
+	if input, ok := inMemoryInputs.instances[name]; ok {
+		return input
+	}
+
+	inMemoryInputs.instances[name] = NewInMemoryInput(settings)
+
+	return inMemoryInputs.instances[name]
+}
+
+// ResetInMemoryInputs removes all named in memory inputs, so every test can start with fresh ones
+func ResetInMemoryInputs() {
+	inMemoryInputs.Lock()
+	defer inMemoryInputs.Unlock()
+
+	inMemoryInputs.instances = make(map[string]*InMemoryInput)
+}
+
+func NewInMemoryInput(settings *InMemorySettings) *InMemoryInput {
+	size := settings.Size
+
+	if size <= 0 {
+		size = defaultInMemoryInputSize
+	}
+
+	return &InMemoryInput{
+		channel: make(chan *Message, size),
+		stop:    make(chan struct{}),
+		acks:    make([]*Message, 0),
+		nacks:   make([]*Message, 0),
+	}
+}
+
+// Publish hands the messages to the consumer of the input. Messages published after the input was stopped are dropped,
+// as are the remaining messages of a publisher which is blocked by a full buffer when the input gets stopped.
+func (i *InMemoryInput) Publish(messages ...*Message) {
+	i.lck.Lock()
+	defer i.lck.Unlock()
+
+	if i.closed {
+		return
+	}
+
+	for _, msg := range messages {
+		select {
+		case i.channel <- msg:
+		case <-i.stop:
+			return
+		}
+	}
+}
+
+func (i *InMemoryInput) Data() chan *Message {
+	return i.channel
+}
+
+func (i *InMemoryInput) Run() error {
+	<-i.stop
+
+	i.lck.Lock()
+	defer i.lck.Unlock()
+
+	i.closed = true
+	close(i.channel)
+
+	return nil
+}
+
+func (i *InMemoryInput) Stop() {
+	i.stopOnce.Do(func() {
+		close(i.stop)
+	})
+}
+
+func (i *InMemoryInput) Ack(msg *Message) error {
+	i.ackLck.Lock()
+	defer i.ackLck.Unlock()
+
+	i.acks = append(i.acks, msg)
+
+	return nil
+}
+
+// Nack publishes the message again after the delay. With the NackDefaultDelay the message is only recorded,
+// as unacknowledged messages of the in memory input are never delivered again.
+func (i *InMemoryInput) Nack(msg *Message, delay time.Duration) error {
+	i.ackLck.Lock()
+	defer i.ackLck.Unlock()
+
+	i.nacks = append(i.nacks, msg)
+
+	if delay < 0 {
+		return nil
+	}
+
+	time.AfterFunc(delay, func() {
+		i.Publish(msg)
+	})
+
+	return nil
+}
+
+func (i *InMemoryInput) Nacks() []*Message {
+	i.ackLck.Lock()
+	defer i.ackLck.Unlock()
+
+	nacks := make([]*Message, len(i.nacks))
+	copy(nacks, i.nacks)
+
+	return nacks
+}
+
+func (i *InMemoryInput) Acks() []*Message {
+	i.ackLck.Lock()
+	defer i.ackLck.Unlock()
+
+	acks := make([]*Message, len(i.acks))
+	copy(acks, i.acks)
+
+	return acks
+}
+
+func (i *InMemoryInput) IsAcked(msg *Message) bool {
+	i.ackLck.Lock()
+	defer i.ackLck.Unlock()
+
+	for _, acked := range i.acks {
+		if acked == msg {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,82 @@
+package stream_test
+
+import (
+	"context"
+	configMocks "github.com/applike/gosoline/pkg/cfg/mocks"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestInMemoryInput_PublishAndAck(t *testing.T) {
+	input := stream.NewInMemoryInput(&stream.InMemorySettings{})
+
+	msg := &stream.Message{
+		Body: "foobar",
+	}
+	input.Publish(msg)
+
+	go func() {
+		_ = input.Run()
+	}()
+
+	received := <-input.Data()
+	assert.Equal(t, msg, received)
+
+	err := input.Ack(received)
+	assert.NoError(t, err)
+	assert.True(t, input.IsAcked(msg))
+	assert.Len(t, input.Acks(), 1)
+
+	input.Stop()
+
+	_, ok := <-input.Data()
+	assert.False(t, ok, "the data channel should be closed after stop")
+}
+
+func TestInMemoryInput_StopWithFullBuffer(t *testing.T) {
+	input := stream.NewInMemoryInput(&stream.InMemorySettings{
+		Size: 1,
+	})
+
+	published := make(chan struct{})
+	go func() {
+		input.Publish(&stream.Message{Body: "foo"}, &stream.Message{Body: "bar"})
+		close(published)
+	}()
+
+	done := make(chan error)
+	go func() {
+		done <- input.Run()
+	}()
+
+	input.Stop()
+
+	assert.NoError(t, <-done)
+	<-published
+}
+
+func TestInMemoryInput_Loopback(t *testing.T) {
+	stream.ResetInMemoryInputs()
+
+	config := new(configMocks.Config)
+	config.On("GetString", "input_consumer_type").Return(stream.InputTypeInMemory)
+	config.On("IsSet", "input_consumer_settings").Return(false)
+
+	logger := monMocks.NewLoggerMockedAll()
+
+	input := stream.NewConfigurableInput(config, logger, "consumer")
+	assert.Equal(t, stream.ProvideInMemoryInput("consumer", nil), input)
+
+	output := stream.NewOutputMemoryLoopback(stream.ProvideInMemoryInput("consumer", nil))
+
+	msg := &stream.Message{
+		Body: "foobar",
+	}
+	err := output.WriteOne(context.Background(), msg)
+
+	assert.NoError(t, err)
+	assert.True(t, output.ContainsBody("foobar"))
+	assert.Equal(t, msg, <-input.Data())
+}
@@ -78,19 +78,37 @@ func (i *kinsumerInput) Run() error {
 			}
 
 		case rawMessage != nil: // rawMessage received
-			msg := Message{}
-			err := json.Unmarshal(rawMessage, &msg)
+			for _, data := range i.deaggregate(rawMessage) {
+				msg := Message{}
+				err := json.Unmarshal(data, &msg)
 
-			if err != nil {
-				i.logger.Error(err, "could not unmarshal message")
-				continue
-			}
+				if err != nil {
+					i.logger.Error(err, "could not unmarshal message")
+					continue
+				}
 
-			i.channel <- &msg
+				i.channel <- &msg
+			}
 		}
 	}
 }
 
+// deaggregate returns the records of a record aggregated by the kinesis producer library or the record itself
+func (i *kinsumerInput) deaggregate(rawMessage []byte) [][]byte {
+	if !isAggregatedKinesisRecord(rawMessage) {
+		return [][]byte{rawMessage}
+	}
+
+	records, err := deaggregateKinesisRecord(rawMessage)
+
+	if err != nil {
+		i.logger.Warnf("could not deaggregate the record, handling it as single record: %s", err.Error())
+		return [][]byte{rawMessage}
+	}
+
+	return records
+}
+
 func (i *kinsumerInput) Stop() {
 	i.client.Stop()
 	i.wg.Wait()
@@ -9,6 +9,8 @@ import (
 	"time"
 )
 
+const metricNameKinesisInputMillisBehindLatest = "StreamKinesisInputMillisBehindLatest"
+
 type Kinsumer interface {
 	Run() error
 	Next() (data []byte, err error)
@@ -35,6 +37,7 @@ func NewKinsumer(config cfg.Config, logger mon.Logger, settings KinsumerSettings
 	kinsumerConfig := kinsumer.NewConfig()
 	kinsumerConfig.WithShardCheckFrequency(shardCheckFreq)
 	kinsumerConfig.WithLeaderActionFrequency(leaderActionFreq)
+	kinsumerConfig = kinsumerConfig.WithStats(newKinsumerStats(mon.NewMetricDaemonWriter(), settings.StreamName))
 
 	client, err := kinsumer.NewWithInterfaces(kinesisClient, dynamoDbClient, settings.StreamName, settings.ApplicationName, clientName, kinsumerConfig)
 
@@ -50,3 +53,30 @@ func NewKinsumer(config cfg.Config, logger mon.Logger, settings KinsumerSettings
 
 	return client
 }
+
+// kinsumerStats reports how far behind the tip of the stream the records read from every shard are
+type kinsumerStats struct {
+	kinsumer.NoopStatReceiver
+	mw         mon.MetricWriter
+	streamName string
+}
+
+func newKinsumerStats(mw mon.MetricWriter, streamName string) *kinsumerStats {
+	return &kinsumerStats{
+		mw:         mw,
+		streamName: streamName,
+	}
+}
+
+func (s *kinsumerStats) EventsFromKinesis(_ int, shardId string, lag time.Duration) {
+	s.mw.WriteOne(&mon.MetricDatum{
+		Priority:   mon.PriorityHigh,
+		MetricName: metricNameKinesisInputMillisBehindLatest,
+		Dimensions: map[string]string{
+			"StreamName": s.streamName,
+			"ShardId":    shardId,
+		},
+		Unit:  mon.UnitMilliseconds,
+		Value: float64(lag.Nanoseconds()) / float64(time.Millisecond),
+	})
+}
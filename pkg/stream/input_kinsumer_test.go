@@ -1,14 +1,17 @@
 package stream_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/applike/gosoline/pkg/cfg"
 	configMocks "github.com/applike/gosoline/pkg/cfg/mocks"
+	cloudMocks "github.com/applike/gosoline/pkg/cloud/mocks"
 	"github.com/applike/gosoline/pkg/mon"
 	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
 	"github.com/applike/gosoline/pkg/stream"
 	streamMocks "github.com/applike/gosoline/pkg/stream/mocks"
+	"github.com/aws/aws-sdk-go/service/kinesis"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -127,3 +130,54 @@ func TestReaderRestartTrigger(t *testing.T) {
 	assert.Equal(t, msg, *out, "the messages should match")
 	kinsumerMock.AssertExpectations(t)
 }
+
+func TestReaderDeaggregation(t *testing.T) {
+	var records []*kinesis.PutRecordsRequestEntry
+
+	kinesisClient := new(cloudMocks.KinesisAPI)
+	kinesisClient.On("PutRecords", mock.Anything).Run(func(args mock.Arguments) {
+		records = args.Get(0).(*kinesis.PutRecordsInput).Records
+	}).Return(&kinesis.PutRecordsOutput{
+		Records: []*kinesis.PutRecordsResultEntry{{}},
+	}, nil).Once()
+
+	batch := []*stream.Message{
+		{Attributes: map[string]interface{}{}, Body: "1"},
+		{Attributes: map[string]interface{}{}, Body: "2"},
+	}
+
+	writer := stream.NewKinesisOutputWithInterfaces(monMocks.NewLoggerMockedAll(), kinesisClient, &stream.KinesisOutputSettings{
+		Aggregation: true,
+	})
+	err := writer.Write(context.Background(), batch)
+
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+
+	kinsumerMock := new(streamMocks.Kinsumer)
+	kinsumerMock.On("Run").Return(nil)
+	kinsumerMock.On("Next").Return(records[0].Data, nil).Once()
+	kinsumerMock.On("Next").Return(nil, nil).Once()
+	kinsumerMock.On("Stop")
+
+	factory := func(config cfg.Config, logger mon.Logger, settings stream.KinsumerSettings) stream.Kinsumer {
+		return kinsumerMock
+	}
+
+	reader := stream.NewKinsumerInput(new(configMocks.Config), monMocks.NewLoggerMockedAll(), factory, stream.KinsumerSettings{})
+
+	go func() {
+		err = reader.Run()
+	}()
+
+	out := make([]*stream.Message, 0)
+	for msg := range reader.Data() {
+		out = append(out, msg)
+	}
+
+	reader.Stop()
+
+	assert.NoError(t, err)
+	assert.Equal(t, batch, out, "the aggregated messages should be read in order")
+	kinsumerMock.AssertExpectations(t)
+}
@@ -0,0 +1,443 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/coffin"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/applike/gosoline/pkg/redis"
+	"github.com/cenkalti/backoff"
+	baseRedis "github.com/go-redis/redis"
+	"github.com/twinj/uuid"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AttributeRedisStreamId = "redisStreamId"
+
+	metricNameRedisStreamInputLength = "StreamRedisStreamInputLength"
+	metricNameRedisStreamInputReads  = "StreamRedisStreamInputReads"
+	metricNameRedisStreamInputClaims = "StreamRedisStreamInputClaims"
+	metricNameRedisStreamInputDrops  = "StreamRedisStreamInputDrops"
+
+	redisStreamMessageField            = "message"
+	redisStreamDefaultBatchSize        = 10
+	redisStreamDefaultBlockTime        = time.Second
+	redisStreamDefaultClaimInterval    = 10 * time.Second
+	redisStreamDefaultClaimMinIdle     = time.Minute
+	redisStreamDefaultMaxDeliveries    = 10
+	redisStreamDefaultReadRetryWait    = 100 * time.Millisecond
+	redisStreamDefaultReadRetryMaxWait = 10 * time.Second
+)
+
+type RedisStreamInputSettings struct {
+	cfg.AppId
+	ServerName string
+	Key        string
+	// Group is the consumer group sharing the messages of the stream, defaults to the name of the application
+	Group string
+	// Consumer identifies the reader within the group, defaults to a random id
+	Consumer  string
+	BatchSize int64
+	BlockTime time.Duration
+	// Messages which were delivered but not acknowledged for ClaimMinIdle are claimed and delivered again,
+	// the pending messages are checked every ClaimInterval
+	ClaimInterval time.Duration
+	ClaimMinIdle  time.Duration
+	// MaxDeliveries limits how often a message is delivered, pending messages which were delivered as often are
+	// acknowledged and dropped instead of being claimed again
+	MaxDeliveries int64
+}
+
+type redisStreamInput struct {
+	logger   mon.Logger
+	mw       mon.MetricWriter
+	client   redis.Client
+	settings *RedisStreamInputSettings
+
+	cfn               coffin.Coffin
+	channel           chan *Message
+	stop              chan struct{}
+	stopOnce          sync.Once
+	fullyQualifiedKey string
+}
+
+func NewRedisStreamInput(config cfg.Config, logger mon.Logger, settings *RedisStreamInputSettings) Input {
+	settings.PadFromConfig(config)
+	client := redis.GetClient(config, logger, settings.ServerName)
+
+	defaultMetrics := getRedisStreamInputDefaultMetrics(settings.AppId, settings.Key)
+	mw := mon.NewMetricDaemonWriter(defaultMetrics...)
+
+	return NewRedisStreamInputWithInterfaces(logger, client, mw, settings)
+}
+
+func NewRedisStreamInputWithInterfaces(logger mon.Logger, client redis.Client, mw mon.MetricWriter, settings *RedisStreamInputSettings) Input {
+	if settings.Group == "" {
+		settings.Group = settings.Application
+	}
+
+	if settings.Consumer == "" {
+		settings.Consumer = uuid.NewV4().String()
+	}
+
+	if settings.BatchSize <= 0 {
+		settings.BatchSize = redisStreamDefaultBatchSize
+	}
+
+	if settings.BlockTime <= 0 {
+		settings.BlockTime = redisStreamDefaultBlockTime
+	}
+
+	if settings.ClaimInterval <= 0 {
+		settings.ClaimInterval = redisStreamDefaultClaimInterval
+	}
+
+	if settings.ClaimMinIdle <= 0 {
+		settings.ClaimMinIdle = redisStreamDefaultClaimMinIdle
+	}
+
+	if settings.MaxDeliveries <= 0 {
+		settings.MaxDeliveries = redisStreamDefaultMaxDeliveries
+	}
+
+	fullyQualifiedKey := redis.GetFullyQualifiedKey(settings.AppId, settings.Key)
+
+	return &redisStreamInput{
+		logger:            logger,
+		mw:                mw,
+		client:            client,
+		settings:          settings,
+		cfn:               coffin.New(),
+		channel:           make(chan *Message),
+		stop:              make(chan struct{}),
+		fullyQualifiedKey: fullyQualifiedKey,
+	}
+}
+
+func (i *redisStreamInput) Data() chan *Message {
+	return i.channel
+}
+
+func (i *redisStreamInput) Run() error {
+	defer close(i.channel)
+
+	// the group starts at the beginning of the stream, so messages written before the first consumer started aren't lost
+	err := i.client.XGroupCreateMkStream(i.fullyQualifiedKey, i.settings.Group, "0")
+
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		i.logger.Errorf(err, "could not create the consumer group %s of the redis stream %s", i.settings.Group, i.fullyQualifiedKey)
+		return err
+	}
+
+	i.cfn.Gof(i.readLoop, "panic in redis stream input reader")
+	i.cfn.Gof(i.claimLoop, "panic in redis stream input claimer")
+
+	<-i.cfn.Dying()
+	i.Stop()
+
+	return i.cfn.Wait()
+}
+
+func (i *redisStreamInput) readLoop() error {
+	retryBackOff := backoff.NewExponentialBackOff()
+	retryBackOff.InitialInterval = redisStreamDefaultReadRetryWait
+	retryBackOff.MaxInterval = redisStreamDefaultReadRetryMaxWait
+	retryBackOff.MaxElapsedTime = 0
+	retryBackOff.Reset()
+
+	for {
+		if i.isStopped() {
+			return nil
+		}
+
+		streams, err := i.client.XReadGroup(&baseRedis.XReadGroupArgs{
+			Group:    i.settings.Group,
+			Consumer: i.settings.Consumer,
+			Streams:  []string{i.fullyQualifiedKey, ">"},
+			Count:    i.settings.BatchSize,
+			Block:    i.settings.BlockTime,
+		})
+
+		if err != nil && err.Error() != redis.Nil.Error() {
+			wait := retryBackOff.NextBackOff()
+			i.logger.Errorf(err, "could not XReadGroup from redis, retrying in %v", wait)
+
+			select {
+			case <-i.stop:
+				return nil
+			case <-time.After(wait):
+			}
+
+			continue
+		}
+
+		retryBackOff.Reset()
+
+		for _, stream := range streams {
+			i.deliver(stream.Messages)
+			i.writeMetric(metricNameRedisStreamInputReads, len(stream.Messages))
+		}
+	}
+}
+
+func (i *redisStreamInput) claimLoop() error {
+	ticker := time.NewTicker(i.settings.ClaimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-i.stop:
+			return nil
+		case <-ticker.C:
+		}
+
+		if err := i.claim(); err != nil {
+			i.logger.Error(err, "could not claim the pending messages of the redis stream")
+		}
+
+		i.writeStreamLengthMetric()
+	}
+}
+
+// claim pages through the pending entries of the group and takes over the messages which other consumers of the
+// group received but didn't acknowledge in time
+func (i *redisStreamInput) claim() error {
+	start := "-"
+
+	for {
+		if i.isStopped() {
+			return nil
+		}
+
+		pending, err := i.client.XPendingExt(&baseRedis.XPendingExtArgs{
+			Stream: i.fullyQualifiedKey,
+			Group:  i.settings.Group,
+			Start:  start,
+			End:    "+",
+			Count:  i.settings.BatchSize,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		if err := i.claimPending(pending); err != nil {
+			return err
+		}
+
+		if int64(len(pending)) < i.settings.BatchSize {
+			return nil
+		}
+
+		if start, err = nextRedisStreamId(pending[len(pending)-1].Id); err != nil {
+			return err
+		}
+	}
+}
+
+// claimPending claims the idle messages of the page and drops the ones which were delivered MaxDeliveries times
+func (i *redisStreamInput) claimPending(pending []baseRedis.XPendingExt) error {
+	ids := make([]string, 0, len(pending))
+	dropped := 0
+
+	for _, p := range pending {
+		if p.Idle < i.settings.ClaimMinIdle {
+			continue
+		}
+
+		if p.RetryCount >= i.settings.MaxDeliveries {
+			i.logger.Warnf("dropping the redis stream message %s after %d deliveries", p.Id, p.RetryCount)
+			i.ack(p.Id)
+			dropped++
+
+			continue
+		}
+
+		ids = append(ids, p.Id)
+	}
+
+	i.writeMetric(metricNameRedisStreamInputDrops, dropped)
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	messages, err := i.client.XClaim(&baseRedis.XClaimArgs{
+		Stream:   i.fullyQualifiedKey,
+		Group:    i.settings.Group,
+		Consumer: i.settings.Consumer,
+		MinIdle:  i.settings.ClaimMinIdle,
+		Messages: ids,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	i.deliver(messages)
+	i.writeMetric(metricNameRedisStreamInputClaims, len(messages))
+
+	return nil
+}
+
+// nextRedisStreamId returns the smallest id after the given one, as the range of XPENDING includes its start
+func nextRedisStreamId(id string) (string, error) {
+	parts := strings.SplitN(id, "-", 2)
+
+	if len(parts) != 2 {
+		return "", fmt.Errorf("the redis stream id %s has no sequence number", id)
+	}
+
+	sequence, err := strconv.ParseUint(parts[1], 10, 64)
+
+	if err != nil {
+		return "", fmt.Errorf("the sequence number of the redis stream id %s is invalid: %s", id, err.Error())
+	}
+
+	return fmt.Sprintf("%s-%d", parts[0], sequence+1), nil
+}
+
+func (i *redisStreamInput) deliver(messages []baseRedis.XMessage) {
+	for _, xMessage := range messages {
+		msg, err := i.decode(xMessage)
+
+		if err != nil {
+			// a message which can't be decoded would be claimed again and again, so it is removed from the pending entries
+			i.logger.Errorf(err, "could not unmarshal the redis stream message %s", xMessage.ID)
+			i.ack(xMessage.ID)
+
+			continue
+		}
+
+		// an undelivered message stays pending and is claimed by another consumer of the group
+		select {
+		case i.channel <- msg:
+		case <-i.stop:
+			return
+		}
+	}
+}
+
+func (i *redisStreamInput) decode(xMessage baseRedis.XMessage) (*Message, error) {
+	rawMessage, ok := xMessage.Values[redisStreamMessageField].(string)
+
+	if !ok {
+		return nil, fmt.Errorf("the field %s of the message should be string but instead is %T", redisStreamMessageField, xMessage.Values[redisStreamMessageField])
+	}
+
+	msg := &Message{}
+
+	if err := json.Unmarshal([]byte(rawMessage), msg); err != nil {
+		return nil, err
+	}
+
+	if msg.Attributes == nil {
+		msg.Attributes = make(map[string]interface{})
+	}
+
+	msg.Attributes[AttributeRedisStreamId] = xMessage.ID
+
+	return msg, nil
+}
+
+func (i *redisStreamInput) Stop() {
+	i.stopOnce.Do(func() {
+		close(i.stop)
+	})
+}
+
+func (i *redisStreamInput) isStopped() bool {
+	select {
+	case <-i.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (i *redisStreamInput) Ack(msg *Message) error {
+	id, ok := msg.Attributes[AttributeRedisStreamId].(string)
+
+	if !ok {
+		return fmt.Errorf("the message has no attribute %s", AttributeRedisStreamId)
+	}
+
+	_, err := i.client.XAck(i.fullyQualifiedKey, i.settings.Group, id)
+
+	return err
+}
+
+func (i *redisStreamInput) ack(id string) {
+	if _, err := i.client.XAck(i.fullyQualifiedKey, i.settings.Group, id); err != nil {
+		i.logger.Errorf(err, "could not acknowledge the redis stream message %s", id)
+	}
+}
+
+// Nack leaves the message pending, so it is claimed and delivered again once it was idle for ClaimMinIdle.
+// Redis streams can't delay a single message, so the delay is ignored.
+func (i *redisStreamInput) Nack(_ *Message, _ time.Duration) error {
+	return nil
+}
+
+func (i *redisStreamInput) writeStreamLengthMetric() {
+	length, err := i.client.XLen(i.fullyQualifiedKey)
+
+	if err != nil {
+		i.logger.Error(err, "can not publish the length metric of the redis stream")
+		return
+	}
+
+	data := mon.MetricData{{
+		Priority:   mon.PriorityHigh,
+		MetricName: metricNameRedisStreamInputLength,
+		Dimensions: map[string]string{
+			"StreamName": i.fullyQualifiedKey,
+		},
+		Unit:  mon.UnitCountAverage,
+		Value: float64(length),
+	}}
+
+	i.mw.Write(data)
+}
+
+func (i *redisStreamInput) writeMetric(metricName string, count int) {
+	if count == 0 {
+		return
+	}
+
+	data := mon.MetricData{{
+		MetricName: metricName,
+		Dimensions: map[string]string{
+			"StreamName": i.fullyQualifiedKey,
+		},
+		Unit:  mon.UnitCount,
+		Value: float64(count),
+	}}
+
+	i.mw.Write(data)
+}
+
+func getRedisStreamInputDefaultMetrics(appId cfg.AppId, key string) mon.MetricData {
+	fullyQualifiedKey := redis.GetFullyQualifiedKey(appId, key)
+
+	defaults := mon.MetricData{}
+
+	for _, metricName := range []string{metricNameRedisStreamInputReads, metricNameRedisStreamInputClaims, metricNameRedisStreamInputDrops} {
+		defaults = append(defaults, &mon.MetricDatum{
+			Priority:   mon.PriorityHigh,
+			MetricName: metricName,
+			Dimensions: map[string]string{
+				"StreamName": fullyQualifiedKey,
+			},
+			Unit:  mon.UnitCount,
+			Value: 0.0,
+		})
+	}
+
+	return defaults
+}
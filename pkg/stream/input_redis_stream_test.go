@@ -0,0 +1,238 @@
+package stream_test
+
+import (
+	"errors"
+	"github.com/applike/gosoline/pkg/cfg"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/redis"
+	redisMocks "github.com/applike/gosoline/pkg/redis/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	baseRedis "github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+const redisStreamTestKey = "mcoins-test-analytics-app-my-stream"
+
+func buildRedisStreamInput(client *redisMocks.Client) stream.Input {
+	return buildRedisStreamInputWithLimits(client, 0, 0)
+}
+
+func buildRedisStreamInputWithLimits(client *redisMocks.Client, batchSize int64, maxDeliveries int64) stream.Input {
+	logger := monMocks.NewLoggerMockedAll()
+	mw := monMocks.NewMetricWriterMockedAll()
+
+	settings := &stream.RedisStreamInputSettings{
+		AppId: cfg.AppId{
+			Project:     "mcoins",
+			Environment: "test",
+			Family:      "analytics",
+			Application: "app",
+		},
+		Key:           "my-stream",
+		Consumer:      "consumer",
+		BatchSize:     batchSize,
+		ClaimInterval: 10 * time.Millisecond,
+		ClaimMinIdle:  time.Minute,
+		MaxDeliveries: maxDeliveries,
+	}
+
+	return stream.NewRedisStreamInputWithInterfaces(logger, client, mw, settings)
+}
+
+func TestRedisStreamInput_Run(t *testing.T) {
+	client := new(redisMocks.Client)
+	client.On("XGroupCreateMkStream", redisStreamTestKey, "app", "0").Return(errors.New("BUSYGROUP Consumer Group name already exists")).Once()
+	client.On("XReadGroup", mock.MatchedBy(func(args *baseRedis.XReadGroupArgs) bool {
+		return args.Group == "app" && args.Consumer == "consumer" && args.Streams[0] == redisStreamTestKey && args.Streams[1] == ">"
+	})).Return([]baseRedis.XStream{
+		{
+			Stream: redisStreamTestKey,
+			Messages: []baseRedis.XMessage{
+				{ID: "1-0", Values: map[string]interface{}{"message": `{"body":"foo"}`}},
+				{ID: "2-0", Values: map[string]interface{}{"message": `not json`}},
+			},
+		},
+	}, nil).Once()
+	client.On("XReadGroup", mock.Anything).Return(nil, redis.Nil).After(time.Millisecond)
+	client.On("XPendingExt", mock.Anything).Return([]baseRedis.XPendingExt{}, nil).Maybe()
+	client.On("XLen", redisStreamTestKey).Return(int64(2), nil).Maybe()
+	client.On("XAck", redisStreamTestKey, "app", "2-0").Return(int64(1), nil).Once()
+	client.On("XAck", redisStreamTestKey, "app", "1-0").Return(int64(1), nil).Once()
+
+	input := buildRedisStreamInput(client)
+
+	done := make(chan error)
+	go func() {
+		done <- input.Run()
+	}()
+
+	msg := <-input.Data()
+
+	assert.Equal(t, "foo", msg.Body)
+	assert.Equal(t, "1-0", msg.Attributes[stream.AttributeRedisStreamId])
+
+	err := input.(stream.AcknowledgeableInput).Ack(msg)
+	assert.NoError(t, err)
+
+	input.Stop()
+
+	assert.NoError(t, <-done)
+	client.AssertExpectations(t)
+}
+
+func TestRedisStreamInput_Claim(t *testing.T) {
+	client := new(redisMocks.Client)
+	client.On("XGroupCreateMkStream", redisStreamTestKey, "app", "0").Return(nil).Once()
+	client.On("XReadGroup", mock.Anything).Return(nil, redis.Nil).After(time.Millisecond)
+	client.On("XPendingExt", mock.Anything).Return([]baseRedis.XPendingExt{
+		{Id: "1-0", Consumer: "crashed", Idle: 2 * time.Minute},
+		{Id: "2-0", Consumer: "other", Idle: time.Second},
+	}, nil).Once()
+	client.On("XPendingExt", mock.Anything).Return([]baseRedis.XPendingExt{}, nil)
+	client.On("XLen", redisStreamTestKey).Return(int64(2), nil)
+	client.On("XClaim", mock.MatchedBy(func(args *baseRedis.XClaimArgs) bool {
+		return args.Consumer == "consumer" && args.MinIdle == time.Minute && assert.ObjectsAreEqual([]string{"1-0"}, args.Messages)
+	})).Return([]baseRedis.XMessage{
+		{ID: "1-0", Values: map[string]interface{}{"message": `{"body":"foo"}`}},
+	}, nil).Once()
+
+	input := buildRedisStreamInput(client)
+
+	done := make(chan error)
+	go func() {
+		done <- input.Run()
+	}()
+
+	msg := <-input.Data()
+
+	assert.Equal(t, "foo", msg.Body)
+	assert.Equal(t, "1-0", msg.Attributes[stream.AttributeRedisStreamId])
+
+	input.Stop()
+
+	assert.NoError(t, <-done)
+	client.AssertExpectations(t)
+}
+
+func TestRedisStreamInput_ClaimPagesAndDrops(t *testing.T) {
+	client := new(redisMocks.Client)
+	client.On("XGroupCreateMkStream", redisStreamTestKey, "app", "0").Return(nil).Once()
+	client.On("XReadGroup", mock.Anything).Return(nil, redis.Nil).After(time.Millisecond)
+	client.On("XPendingExt", mock.MatchedBy(func(args *baseRedis.XPendingExtArgs) bool {
+		return args.Start == "-" && args.Count == 2
+	})).Return([]baseRedis.XPendingExt{
+		{Id: "1-0", Consumer: "crashed", Idle: 2 * time.Minute, RetryCount: 1},
+		{Id: "2-0", Consumer: "crashed", Idle: 2 * time.Minute, RetryCount: 3},
+	}, nil).Once()
+	client.On("XPendingExt", mock.MatchedBy(func(args *baseRedis.XPendingExtArgs) bool {
+		return args.Start == "2-1"
+	})).Return([]baseRedis.XPendingExt{
+		{Id: "3-0", Consumer: "crashed", Idle: 2 * time.Minute, RetryCount: 2},
+	}, nil).Once()
+	client.On("XPendingExt", mock.Anything).Return([]baseRedis.XPendingExt{}, nil)
+	client.On("XLen", redisStreamTestKey).Return(int64(3), nil)
+	client.On("XAck", redisStreamTestKey, "app", "2-0").Return(int64(1), nil).Once()
+	client.On("XClaim", mock.MatchedBy(func(args *baseRedis.XClaimArgs) bool {
+		return assert.ObjectsAreEqual([]string{"1-0"}, args.Messages)
+	})).Return([]baseRedis.XMessage{
+		{ID: "1-0", Values: map[string]interface{}{"message": `{"body":"foo"}`}},
+	}, nil).Once()
+	client.On("XClaim", mock.MatchedBy(func(args *baseRedis.XClaimArgs) bool {
+		return assert.ObjectsAreEqual([]string{"3-0"}, args.Messages)
+	})).Return([]baseRedis.XMessage{
+		{ID: "3-0", Values: map[string]interface{}{"message": `{"body":"bar"}`}},
+	}, nil).Once()
+
+	input := buildRedisStreamInputWithLimits(client, 2, 3)
+
+	done := make(chan error)
+	go func() {
+		done <- input.Run()
+	}()
+
+	assert.Equal(t, "foo", (<-input.Data()).Body)
+	assert.Equal(t, "bar", (<-input.Data()).Body, "the next page of pending messages should be claimed")
+
+	input.Stop()
+
+	assert.NoError(t, <-done)
+	client.AssertExpectations(t)
+}
+
+func TestRedisStreamInput_ReadError(t *testing.T) {
+	client := new(redisMocks.Client)
+	client.On("XGroupCreateMkStream", redisStreamTestKey, "app", "0").Return(nil).Once()
+	client.On("XReadGroup", mock.Anything).Return(nil, errors.New("connection refused")).Once()
+	client.On("XReadGroup", mock.Anything).Return([]baseRedis.XStream{
+		{
+			Stream: redisStreamTestKey,
+			Messages: []baseRedis.XMessage{
+				{ID: "1-0", Values: map[string]interface{}{"message": `{"body":"foo"}`}},
+			},
+		},
+	}, nil).Once()
+	client.On("XReadGroup", mock.Anything).Return(nil, redis.Nil).After(time.Millisecond)
+	client.On("XPendingExt", mock.Anything).Return([]baseRedis.XPendingExt{}, nil).Maybe()
+	client.On("XLen", redisStreamTestKey).Return(int64(1), nil).Maybe()
+
+	input := buildRedisStreamInput(client)
+
+	done := make(chan error)
+	go func() {
+		done <- input.Run()
+	}()
+
+	assert.Equal(t, "foo", (<-input.Data()).Body, "the input should keep reading after an error")
+
+	input.Stop()
+
+	assert.NoError(t, <-done)
+	client.AssertExpectations(t)
+}
+
+func TestRedisStreamInput_StopWhileRetrying(t *testing.T) {
+	client := new(redisMocks.Client)
+	client.On("XGroupCreateMkStream", redisStreamTestKey, "app", "0").Return(nil).Once()
+	client.On("XReadGroup", mock.Anything).Return(nil, errors.New("connection refused"))
+
+	settings := &stream.RedisStreamInputSettings{
+		AppId: cfg.AppId{
+			Project:     "mcoins",
+			Environment: "test",
+			Family:      "analytics",
+			Application: "app",
+		},
+		Key:           "my-stream",
+		ClaimInterval: time.Hour,
+	}
+	input := stream.NewRedisStreamInputWithInterfaces(monMocks.NewLoggerMockedAll(), client, monMocks.NewMetricWriterMockedAll(), settings)
+
+	done := make(chan error)
+	go func() {
+		done <- input.Run()
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	input.Stop()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		assert.Fail(t, "the input should stop without waiting for the claim interval or the read retry")
+	}
+}
+
+func TestRedisStreamInput_RunError(t *testing.T) {
+	client := new(redisMocks.Client)
+	client.On("XGroupCreateMkStream", redisStreamTestKey, "app", "0").Return(errors.New("connection refused")).Once()
+
+	input := buildRedisStreamInput(client)
+	err := input.Run()
+
+	assert.Error(t, err)
+	client.AssertExpectations(t)
+}
@@ -15,6 +15,11 @@ type SnsInputSettings struct {
 	RedrivePolicy     sqs.RedrivePolicy `cfg:"redrive_policy"`
 	VisibilityTimeout int               `cfg:"visibility_timeout"`
 	RunnerCount       int               `cfg:"runner_count"`
+	Offload           OffloadSettings   `cfg:"offload"`
+	// VisibilityHeartbeat extends the visibility timeout of messages in flight
+	VisibilityHeartbeat SqsVisibilityHeartbeatSettings `cfg:"visibility_heartbeat"`
+	// AckBatch deletes acknowledged messages in batches
+	AckBatch SqsAckBatchSettings `cfg:"ack_batch"`
 }
 
 type SnsInputTarget struct {
@@ -31,12 +36,15 @@ func NewSnsInput(config cfg.Config, logger mon.Logger, s SnsInputSettings, targe
 	s.AutoSubscribe = config.GetBool("aws_sns_autoSubscribe")
 
 	sqsInput := NewSqsInput(config, logger, SqsInputSettings{
-		AppId:             s.AppId,
-		QueueId:           s.QueueId,
-		WaitTime:          s.WaitTime,
-		RedrivePolicy:     s.RedrivePolicy,
-		VisibilityTimeout: s.VisibilityTimeout,
-		RunnerCount:       s.RunnerCount,
+		AppId:               s.AppId,
+		QueueId:             s.QueueId,
+		WaitTime:            s.WaitTime,
+		RedrivePolicy:       s.RedrivePolicy,
+		VisibilityTimeout:   s.VisibilityTimeout,
+		RunnerCount:         s.RunnerCount,
+		Offload:             s.Offload,
+		VisibilityHeartbeat: s.VisibilityHeartbeat,
+		AckBatch:            s.AckBatch,
 	})
 	sqsInput.SetUnmarshaler(SnsUnmarshaler)
 
@@ -6,8 +6,31 @@ import (
 	"github.com/applike/gosoline/pkg/coffin"
 	"github.com/applike/gosoline/pkg/mon"
 	"github.com/applike/gosoline/pkg/sqs"
+	"sync"
+	"time"
 )
 
+const (
+	metricNameSqsInputVisibleMessages  = "StreamSqsInputVisibleMessages"
+	metricNameSqsInputInFlightMessages = "StreamSqsInputInFlightMessages"
+)
+
+const (
+	sqsDefaultVisibilityTimeout = 30
+	sqsMaxVisibilityDuration    = 12 * 60 * 60
+	sqsMetricInterval           = time.Minute
+)
+
+// SqsVisibilityHeartbeatSettings extend the visibility timeout of received messages until they are acknowledged,
+// so messages which take longer to consume than the visibility timeout aren't delivered twice
+type SqsVisibilityHeartbeatSettings struct {
+	Enabled bool `cfg:"enabled"`
+	// Interval in seconds between two extensions, defaults to half of the visibility timeout
+	Interval int `cfg:"interval"`
+	// MaxDuration in seconds after which the visibility isn't extended anymore, defaults to 12 hours
+	MaxDuration int `cfg:"max_duration"`
+}
+
 type SqsInputSettings struct {
 	cfg.AppId
 	QueueId           string            `cfg:"queue_id"`
@@ -16,13 +39,24 @@ type SqsInputSettings struct {
 	RedrivePolicy     sqs.RedrivePolicy `cfg:"redrive_policy"`
 	VisibilityTimeout int               `cfg:"visibility_timeout"`
 	RunnerCount       int               `cfg:"runner_count"`
+	Offload           OffloadSettings   `cfg:"offload"`
+	// VisibilityHeartbeat extends the visibility timeout of messages in flight
+	VisibilityHeartbeat SqsVisibilityHeartbeatSettings `cfg:"visibility_heartbeat"`
+	// AckBatch deletes acknowledged messages in batches
+	AckBatch SqsAckBatchSettings `cfg:"ack_batch"`
 }
 
 type sqsInput struct {
 	logger      mon.Logger
+	mw          mon.MetricWriter
 	queue       sqs.Queue
 	settings    SqsInputSettings
 	unmarshaler MessageUnmarshaler
+	offloader   *MessageOffloader
+	ackBatcher  *sqsAckBatcher
+
+	heartbeatLck sync.Mutex
+	heartbeats   map[string]chan struct{}
 
 	cfn     coffin.Coffin
 	channel chan *Message
@@ -38,22 +72,62 @@ func NewSqsInput(config cfg.Config, logger mon.Logger, s SqsInputSettings) *sqsI
 		VisibilityTimeout: s.VisibilityTimeout,
 	})
 
-	return NewSqsInputWithInterfaces(logger, queue, s)
+	mw := mon.NewMetricDaemonWriter()
+
+	input := NewSqsInputWithInterfaces(logger, queue, mw, s)
+
+	if s.Offload.Enabled {
+		input.SetMessageOffloader(NewMessageOffloader(config, logger, s.Offload))
+	}
+
+	return input
 }
 
-func NewSqsInputWithInterfaces(logger mon.Logger, queue sqs.Queue, s SqsInputSettings) *sqsInput {
+func NewSqsInputWithInterfaces(logger mon.Logger, queue sqs.Queue, mw mon.MetricWriter, s SqsInputSettings) *sqsInput {
 	if s.RunnerCount <= 0 {
 		s.RunnerCount = 1
 	}
 
-	return &sqsInput{
+	if s.VisibilityTimeout <= 0 {
+		s.VisibilityTimeout = sqsDefaultVisibilityTimeout
+	}
+
+	if s.VisibilityHeartbeat.Interval <= 0 {
+		s.VisibilityHeartbeat.Interval = s.VisibilityTimeout / 2
+	}
+
+	if s.VisibilityHeartbeat.Interval <= 0 {
+		s.VisibilityHeartbeat.Interval = 1
+	}
+
+	if s.VisibilityHeartbeat.MaxDuration <= 0 {
+		s.VisibilityHeartbeat.MaxDuration = sqsMaxVisibilityDuration
+	}
+
+	if s.AckBatch.Interval <= 0 {
+		s.AckBatch.Interval = sqsDefaultAckBatchInterval
+	}
+
+	if s.AckBatch.MaxAttempts <= 0 {
+		s.AckBatch.MaxAttempts = sqsDefaultAckBatchAttempts
+	}
+
+	input := &sqsInput{
 		logger:      logger,
+		mw:          mw,
 		queue:       queue,
 		settings:    s,
 		unmarshaler: BasicUnmarshaler,
+		heartbeats:  make(map[string]chan struct{}),
 		cfn:         coffin.New(),
 		channel:     make(chan *Message),
 	}
+
+	if s.AckBatch.Enabled {
+		input.ackBatcher = newSqsAckBatcher(input, s.AckBatch)
+	}
+
+	return input
 }
 
 func (i *sqsInput) Data() chan *Message {
@@ -66,6 +140,16 @@ func (i *sqsInput) Run() error {
 
 	i.logger.Infof("starting sqs input with %d runners", i.settings.RunnerCount)
 
+	done := make(chan struct{})
+	defer close(done)
+
+	go i.runMetricLoop(done)
+
+	if i.ackBatcher != nil {
+		go i.ackBatcher.run(done)
+		defer i.ackBatcher.close()
+	}
+
 	for j := 0; j < i.settings.RunnerCount; j++ {
 		i.cfn.Gof(i.runLoop, "panic in sqs input runner")
 	}
@@ -103,8 +187,23 @@ func (i *sqsInput) runLoop() error {
 				msg.Attributes = make(map[string]interface{})
 			}
 
+			if i.offloader != nil {
+				if err := i.offloader.Restore(msg); err != nil {
+					i.logger.Error(err, "could not restore the offloaded body of the message")
+					continue
+				}
+			}
+
 			msg.Attributes[AttributeSqsReceiptHandle] = *sqsMessage.ReceiptHandle
 
+			if sqsMessage.MessageId != nil {
+				msg.Attributes[AttributeSqsMessageId] = *sqsMessage.MessageId
+			}
+
+			if i.settings.VisibilityHeartbeat.Enabled {
+				i.startHeartbeat(*sqsMessage.ReceiptHandle)
+			}
+
 			i.channel <- msg
 		}
 	}
@@ -115,25 +214,192 @@ func (i *sqsInput) Stop() {
 }
 
 func (i *sqsInput) Ack(msg *Message) error {
+	receiptHandle, err := getSqsReceiptHandle(msg)
+
+	if err != nil {
+		return err
+	}
+
+	i.stopHeartbeat(receiptHandle)
+
+	if i.ackBatcher != nil {
+		return i.ackBatcher.add(msg)
+	}
+
+	if err := i.queue.DeleteMessage(receiptHandle); err != nil {
+		return err
+	}
+
+	i.cleanup(msg)
+
+	return nil
+}
+
+// cleanup removes the offloaded body of a deleted message
+func (i *sqsInput) cleanup(msg *Message) {
+	if i.offloader == nil {
+		return
+	}
+
+	if err := i.offloader.Cleanup(msg); err != nil {
+		i.logger.Error(err, "could not clean up the offloaded body of the message")
+	}
+}
+
+// Nack makes the message visible again after the delay. With the NackDefaultDelay the message becomes visible after
+// the visibility timeout of the queue.
+func (i *sqsInput) Nack(msg *Message, delay time.Duration) error {
+	receiptHandle, err := getSqsReceiptHandle(msg)
+
+	if err != nil {
+		return err
+	}
+
+	heartbeat := i.stopHeartbeat(receiptHandle)
+
+	switch {
+	case delay >= 0:
+		return i.queue.ChangeMessageVisibility(receiptHandle, int64(delay/time.Second))
+	case heartbeat:
+		// the heartbeat could have extended the visibility beyond the visibility timeout
+		return i.queue.ChangeMessageVisibility(receiptHandle, int64(i.settings.VisibilityTimeout))
+	}
+
+	return nil
+}
+
+func (i *sqsInput) startHeartbeat(receiptHandle string) {
+	done := make(chan struct{})
+
+	i.heartbeatLck.Lock()
+	i.heartbeats[receiptHandle] = done
+	i.heartbeatLck.Unlock()
+
+	go i.runHeartbeat(receiptHandle, done)
+}
+
+// stopHeartbeat returns whether there was a heartbeat running for the receipt handle
+func (i *sqsInput) stopHeartbeat(receiptHandle string) bool {
+	i.heartbeatLck.Lock()
+	defer i.heartbeatLck.Unlock()
+
+	done, ok := i.heartbeats[receiptHandle]
+
+	if !ok {
+		return false
+	}
+
+	close(done)
+	delete(i.heartbeats, receiptHandle)
+
+	return true
+}
+
+// stopHeartbeats stops the heartbeats of all messages which were neither acknowledged nor returned to the input
+func (i *sqsInput) stopHeartbeats() {
+	i.heartbeatLck.Lock()
+	defer i.heartbeatLck.Unlock()
+
+	for receiptHandle, done := range i.heartbeats {
+		close(done)
+		delete(i.heartbeats, receiptHandle)
+	}
+}
+
+func (i *sqsInput) runHeartbeat(receiptHandle string, done chan struct{}) {
+	ticker := time.NewTicker(time.Duration(i.settings.VisibilityHeartbeat.Interval) * time.Second)
+	defer ticker.Stop()
+
+	giveUp := time.NewTimer(time.Duration(i.settings.VisibilityHeartbeat.MaxDuration) * time.Second)
+	defer giveUp.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+
+		case <-giveUp.C:
+			i.logger.Warnf("stopped extending the visibility of a message after %d seconds", i.settings.VisibilityHeartbeat.MaxDuration)
+			i.stopHeartbeat(receiptHandle)
+			return
+
+		case <-ticker.C:
+			if err := i.queue.ChangeMessageVisibility(receiptHandle, int64(i.settings.VisibilityTimeout)); err != nil {
+				i.logger.Error(err, "could not extend the visibility of a message")
+			}
+		}
+	}
+}
+
+func (i *sqsInput) runMetricLoop(done chan struct{}) {
+	ticker := time.NewTicker(sqsMetricInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			i.writeMessageCountMetrics()
+		}
+	}
+}
+
+// writeMessageCountMetrics reports the approximate number of messages waiting in the queue and being consumed
+func (i *sqsInput) writeMessageCountMetrics() {
+	counts, err := i.queue.GetMessageCounts()
+
+	if err != nil {
+		i.logger.Error(err, "can not publish the message count metrics of the sqs input")
+		return
+	}
+
+	dimensions := map[string]string{
+		"QueueName": i.queue.GetName(),
+	}
+
+	i.mw.Write(mon.MetricData{
+		{
+			Priority:   mon.PriorityHigh,
+			MetricName: metricNameSqsInputVisibleMessages,
+			Dimensions: dimensions,
+			Unit:       mon.UnitCountAverage,
+			Value:      float64(counts.Visible),
+		}, {
+			Priority:   mon.PriorityHigh,
+			MetricName: metricNameSqsInputInFlightMessages,
+			Dimensions: dimensions,
+			Unit:       mon.UnitCountAverage,
+			Value:      float64(counts.InFlight),
+		},
+	})
+}
+
+func getSqsReceiptHandle(msg *Message) (string, error) {
 	var ok bool
 	var receiptHandleInterface interface{}
 	var receiptHandleString string
 
 	if receiptHandleInterface, ok = msg.Attributes[AttributeSqsReceiptHandle]; !ok {
-		return fmt.Errorf("the message has no attribute %s", AttributeSqsReceiptHandle)
+		return "", fmt.Errorf("the message has no attribute %s", AttributeSqsReceiptHandle)
 	}
 
 	if receiptHandleString, ok = receiptHandleInterface.(string); !ok {
-		return fmt.Errorf("the attribute %s of the message should be string but instead is %T", AttributeSqsReceiptHandle, receiptHandleInterface)
+		return "", fmt.Errorf("the attribute %s of the message should be string but instead is %T", AttributeSqsReceiptHandle, receiptHandleInterface)
 	}
 
-	return i.queue.DeleteMessage(receiptHandleString)
+	return receiptHandleString, nil
 }
 
 func (i *sqsInput) SetUnmarshaler(unmarshaler MessageUnmarshaler) {
 	i.unmarshaler = unmarshaler
 }
 
+// SetMessageOffloader restores the bodies of messages which were written to the blob store by the output
+func (i *sqsInput) SetMessageOffloader(offloader *MessageOffloader) {
+	i.offloader = offloader
+}
+
 func (i *sqsInput) GetQueueUrl() string {
 	return i.queue.GetUrl()
 }
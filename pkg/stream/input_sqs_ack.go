@@ -0,0 +1,210 @@
+package stream
+
+import (
+	"fmt"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/cenkalti/backoff"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"sync"
+	"time"
+)
+
+const (
+	sqsDeleteBatchSize          = 10
+	sqsDefaultAckBatchInterval  = 1
+	sqsDefaultAckBatchAttempts  = 3
+	sqsDefaultAckBatchRetryWait = 100 * time.Millisecond
+)
+
+// SqsAckBatchSettings collect the acknowledged messages and delete them in batches of 10 or after the interval
+type SqsAckBatchSettings struct {
+	Enabled bool `cfg:"enabled"`
+	// Interval in seconds after which the collected messages are deleted, defaults to 1 second
+	Interval int `cfg:"interval"`
+	// MaxAttempts limits how often the deletion of messages which failed to be deleted is tried
+	MaxAttempts int `cfg:"max_attempts"`
+}
+
+type sqsAckBatcher struct {
+	input    *sqsInput
+	settings SqsAckBatchSettings
+
+	lck     sync.Mutex
+	pending []*Message
+	closed  bool
+}
+
+func newSqsAckBatcher(input *sqsInput, settings SqsAckBatchSettings) *sqsAckBatcher {
+	batcher := &sqsAckBatcher{
+		input:    input,
+		settings: settings,
+		pending:  make([]*Message, 0, sqsDeleteBatchSize),
+	}
+
+	return batcher
+}
+
+// add collects the message for the next batch, after the batcher was closed the message is deleted directly
+func (b *sqsAckBatcher) add(msg *Message) error {
+	b.lck.Lock()
+
+	if b.closed {
+		b.lck.Unlock()
+		return b.input.deleteMessages([]*Message{msg})
+	}
+
+	b.pending = append(b.pending, msg)
+
+	if len(b.pending) < sqsDeleteBatchSize {
+		b.lck.Unlock()
+		return nil
+	}
+
+	batch := b.swap()
+	b.lck.Unlock()
+
+	return b.input.deleteMessages(batch)
+}
+
+func (b *sqsAckBatcher) flush() error {
+	b.lck.Lock()
+	batch := b.swap()
+	b.lck.Unlock()
+
+	return b.input.deleteMessages(batch)
+}
+
+func (b *sqsAckBatcher) swap() []*Message {
+	batch := b.pending
+	b.pending = make([]*Message, 0, sqsDeleteBatchSize)
+
+	return batch
+}
+
+func (b *sqsAckBatcher) run(done chan struct{}) {
+	ticker := time.NewTicker(time.Duration(b.settings.Interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		if err := b.flush(); err != nil {
+			b.input.logger.Error(err, "could not delete the acknowledged messages")
+		}
+	}
+}
+
+// close deletes the collected messages, the messages acknowledged afterwards are deleted directly
+func (b *sqsAckBatcher) close() {
+	b.lck.Lock()
+	b.closed = true
+	batch := b.swap()
+	b.lck.Unlock()
+
+	if err := b.input.deleteMessages(batch); err != nil {
+		b.input.logger.Error(err, "could not delete the acknowledged messages")
+	}
+}
+
+// AckBatch deletes the messages in batches of 10 and retries the deletion of messages which failed
+func (i *sqsInput) AckBatch(msgs []*Message) error {
+	return i.deleteMessages(msgs)
+}
+
+// FlushAcks deletes the acknowledged messages which are collected for the next batch. It is called after the last
+// message was consumed, so the heartbeats of messages which were left unacknowledged are stopped as well.
+func (i *sqsInput) FlushAcks() error {
+	i.stopHeartbeats()
+
+	if i.ackBatcher == nil {
+		return nil
+	}
+
+	return i.ackBatcher.flush()
+}
+
+func (i *sqsInput) deleteMessages(msgs []*Message) error {
+	var result error
+
+	for start := 0; start < len(msgs); start += sqsDeleteBatchSize {
+		end := start + sqsDeleteBatchSize
+
+		if end > len(msgs) {
+			end = len(msgs)
+		}
+
+		if err := i.deleteMessageBatch(msgs[start:end]); err != nil {
+			result = multierror.Append(result, err)
+		}
+	}
+
+	return result
+}
+
+func (i *sqsInput) deleteMessageBatch(msgs []*Message) error {
+	pending := make(map[string]*Message, len(msgs))
+
+	for _, msg := range msgs {
+		receiptHandle, err := getSqsReceiptHandle(msg)
+
+		if err != nil {
+			return err
+		}
+
+		i.stopHeartbeat(receiptHandle)
+		pending[receiptHandle] = msg
+	}
+
+	backoffConfig := backoff.NewExponentialBackOff()
+	backoffConfig.InitialInterval = sqsDefaultAckBatchRetryWait
+	retries := uint64(i.settings.AckBatch.MaxAttempts - 1)
+
+	err := backoff.Retry(func() error {
+		receiptHandles := make([]string, 0, len(pending))
+
+		for receiptHandle := range pending {
+			receiptHandles = append(receiptHandles, receiptHandle)
+		}
+
+		failed, err := i.queue.DeleteMessageBatch(receiptHandles)
+
+		isFailed := make(map[string]bool, len(failed))
+		for _, receiptHandle := range failed {
+			isFailed[receiptHandle] = true
+		}
+
+		for _, receiptHandle := range receiptHandles {
+			if isFailed[receiptHandle] {
+				continue
+			}
+
+			i.cleanup(pending[receiptHandle])
+			delete(pending, receiptHandle)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if len(pending) > 0 {
+			return fmt.Errorf("%d messages could not be deleted", len(pending))
+		}
+
+		return nil
+	}, backoff.WithMaxRetries(backoffConfig, retries))
+
+	if err != nil {
+		i.logger.WithFields(mon.Fields{
+			"failed_messages": len(pending),
+		}).Error(err, "could not delete a batch of messages")
+
+		return errors.Wrap(err, "could not delete a batch of messages")
+	}
+
+	return nil
+}
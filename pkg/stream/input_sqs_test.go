@@ -1,13 +1,16 @@
 package stream_test
 
 import (
+	"fmt"
 	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
 	sqsMocks "github.com/applike/gosoline/pkg/sqs/mocks"
 	"github.com/applike/gosoline/pkg/stream"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"testing"
+	"time"
 )
 
 func TestSqsInput_Run(t *testing.T) {
@@ -37,7 +40,7 @@ func TestSqsInput_Run(t *testing.T) {
 		}
 	}, nil)
 
-	input := stream.NewSqsInputWithInterfaces(logger, queue, stream.SqsInputSettings{
+	input := stream.NewSqsInputWithInterfaces(logger, queue, monMocks.NewMetricWriterMockedAll(), stream.SqsInputSettings{
 		WaitTime:    int64(3),
 		RunnerCount: 3,
 	})
@@ -85,7 +88,7 @@ func TestSqsInput_Run_Failure(t *testing.T) {
 		return []*sqs.Message{}
 	}, nil)
 
-	input := stream.NewSqsInputWithInterfaces(logger, queue, stream.SqsInputSettings{
+	input := stream.NewSqsInputWithInterfaces(logger, queue, monMocks.NewMetricWriterMockedAll(), stream.SqsInputSettings{
 		WaitTime:    int64(3),
 		RunnerCount: 3,
 	})
@@ -99,3 +102,235 @@ func TestSqsInput_Run_Failure(t *testing.T) {
 
 	<-waitRunDone
 }
+
+func TestSqsInput_VisibilityHeartbeat(t *testing.T) {
+	logger := monMocks.NewLoggerMockedAll()
+
+	extended := make(chan struct{})
+	queue := new(sqsMocks.Queue)
+	queue.On("ChangeMessageVisibility", "receipt", int64(2)).Run(func(args mock.Arguments) {
+		close(extended)
+	}).Return(nil).Once()
+	queue.On("DeleteMessage", "receipt").Return(nil).Once()
+
+	input := stream.NewSqsInputWithInterfaces(logger, queue, monMocks.NewMetricWriterMockedAll(), stream.SqsInputSettings{
+		VisibilityTimeout: 2,
+		VisibilityHeartbeat: stream.SqsVisibilityHeartbeatSettings{
+			Enabled: true,
+		},
+	})
+
+	queue.On("Receive", int64(0)).Return([]*sqs.Message{
+		{
+			Body:          aws.String(`{"body": "foobar"}`),
+			ReceiptHandle: aws.String("receipt"),
+		},
+	}, nil).Once()
+	queue.On("Receive", int64(0)).Return([]*sqs.Message{}, nil)
+
+	go func() {
+		_ = input.Run()
+	}()
+
+	msg := <-input.Data()
+
+	select {
+	case <-extended:
+	case <-time.After(3 * time.Second):
+		assert.Fail(t, "the visibility of the message should be extended")
+	}
+
+	input.Stop()
+	err := input.Ack(msg)
+
+	assert.NoError(t, err)
+	queue.AssertExpectations(t)
+}
+
+func TestSqsInput_VisibilityHeartbeatAfterStop(t *testing.T) {
+	logger := monMocks.NewLoggerMockedAll()
+
+	extended := make(chan struct{}, 10)
+	queue := new(sqsMocks.Queue)
+	queue.On("Receive", int64(0)).Return([]*sqs.Message{
+		{
+			Body:          aws.String(`{"body": "foobar"}`),
+			ReceiptHandle: aws.String("receipt"),
+		},
+	}, nil).Once()
+	queue.On("Receive", int64(0)).Return([]*sqs.Message{}, nil)
+	queue.On("ChangeMessageVisibility", "receipt", int64(2)).Run(func(args mock.Arguments) {
+		extended <- struct{}{}
+	}).Return(nil)
+
+	input := stream.NewSqsInputWithInterfaces(logger, queue, monMocks.NewMetricWriterMockedAll(), stream.SqsInputSettings{
+		VisibilityTimeout: 2,
+		VisibilityHeartbeat: stream.SqsVisibilityHeartbeatSettings{
+			Enabled: true,
+		},
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- input.Run()
+	}()
+
+	<-input.Data()
+	input.Stop()
+
+	assert.NoError(t, <-done)
+
+	select {
+	case <-extended:
+	case <-time.After(3 * time.Second):
+		assert.Fail(t, "the visibility of a message still being consumed should be extended after the input stopped")
+	}
+
+	err := input.FlushAcks()
+	assert.NoError(t, err)
+
+	calls := len(queue.Calls)
+	time.Sleep(1500 * time.Millisecond)
+
+	assert.Equal(t, calls, len(queue.Calls), "the heartbeats of unacknowledged messages should be stopped by the flush")
+}
+
+func TestSqsInput_Nack(t *testing.T) {
+	logger := monMocks.NewLoggerMockedAll()
+
+	queue := new(sqsMocks.Queue)
+	queue.On("ChangeMessageVisibility", "receipt", int64(60)).Return(nil).Once()
+
+	input := stream.NewSqsInputWithInterfaces(logger, queue, monMocks.NewMetricWriterMockedAll(), stream.SqsInputSettings{})
+
+	err := input.Nack(&stream.Message{
+		Attributes: map[string]interface{}{
+			stream.AttributeSqsReceiptHandle: "receipt",
+		},
+	}, time.Minute)
+
+	assert.NoError(t, err)
+	queue.AssertExpectations(t)
+
+	err = input.Nack(&stream.Message{
+		Attributes: map[string]interface{}{
+			stream.AttributeSqsReceiptHandle: "receipt",
+		},
+	}, stream.NackDefaultDelay)
+
+	assert.NoError(t, err, "without a heartbeat the visibility timeout should be kept")
+	queue.AssertExpectations(t)
+}
+
+func sqsTestMessages(count int) []*stream.Message {
+	msgs := make([]*stream.Message, count)
+
+	for i := range msgs {
+		msgs[i] = &stream.Message{
+			Attributes: map[string]interface{}{
+				stream.AttributeSqsReceiptHandle: fmt.Sprintf("receipt-%d", i),
+			},
+		}
+	}
+
+	return msgs
+}
+
+func TestSqsInput_AckBatch(t *testing.T) {
+	logger := monMocks.NewLoggerMockedAll()
+
+	queue := new(sqsMocks.Queue)
+	queue.On("DeleteMessageBatch", mock.MatchedBy(func(receiptHandles []string) bool {
+		return len(receiptHandles) == 10
+	})).Return([]string{"receipt-3"}, nil).Once()
+	queue.On("DeleteMessageBatch", []string{"receipt-3"}).Return([]string{}, nil).Once()
+	queue.On("DeleteMessageBatch", []string{"receipt-10"}).Return([]string{}, nil).Once()
+
+	input := stream.NewSqsInputWithInterfaces(logger, queue, monMocks.NewMetricWriterMockedAll(), stream.SqsInputSettings{})
+	err := input.AckBatch(sqsTestMessages(11))
+
+	assert.NoError(t, err)
+	queue.AssertExpectations(t)
+}
+
+func TestSqsInput_AckBatchFailure(t *testing.T) {
+	logger := monMocks.NewLoggerMockedAll()
+
+	queue := new(sqsMocks.Queue)
+	queue.On("DeleteMessageBatch", []string{"receipt-0"}).Return([]string{"receipt-0"}, nil).Times(2)
+
+	input := stream.NewSqsInputWithInterfaces(logger, queue, monMocks.NewMetricWriterMockedAll(), stream.SqsInputSettings{
+		AckBatch: stream.SqsAckBatchSettings{
+			MaxAttempts: 2,
+		},
+	})
+	err := input.AckBatch(sqsTestMessages(1))
+
+	assert.Error(t, err)
+	queue.AssertExpectations(t)
+}
+
+func TestSqsInput_AckBatcher(t *testing.T) {
+	logger := monMocks.NewLoggerMockedAll()
+
+	queue := new(sqsMocks.Queue)
+	queue.On("DeleteMessageBatch", mock.MatchedBy(func(receiptHandles []string) bool {
+		return len(receiptHandles) == 10
+	})).Return([]string{}, nil).Once()
+	queue.On("DeleteMessageBatch", []string{"receipt-10"}).Return([]string{}, nil).Once()
+
+	input := stream.NewSqsInputWithInterfaces(logger, queue, monMocks.NewMetricWriterMockedAll(), stream.SqsInputSettings{
+		AckBatch: stream.SqsAckBatchSettings{
+			Enabled:  true,
+			Interval: 3600,
+		},
+	})
+
+	for _, msg := range sqsTestMessages(11) {
+		err := input.Ack(msg)
+		assert.NoError(t, err)
+	}
+
+	queue.AssertNumberOfCalls(t, "DeleteMessageBatch", 1)
+
+	err := input.FlushAcks()
+
+	assert.NoError(t, err)
+	queue.AssertExpectations(t)
+}
+
+func TestSqsInput_AckBatcherFlushedOnStop(t *testing.T) {
+	logger := monMocks.NewLoggerMockedAll()
+
+	queue := new(sqsMocks.Queue)
+	queue.On("Receive", int64(0)).Return([]*sqs.Message{}, nil).Maybe()
+	queue.On("DeleteMessageBatch", []string{"receipt-0"}).Return([]string{}, nil).Once()
+	queue.On("DeleteMessageBatch", []string{"receipt-1"}).Return([]string{}, nil).Once()
+
+	input := stream.NewSqsInputWithInterfaces(logger, queue, monMocks.NewMetricWriterMockedAll(), stream.SqsInputSettings{
+		AckBatch: stream.SqsAckBatchSettings{
+			Enabled:  true,
+			Interval: 3600,
+		},
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- input.Run()
+	}()
+
+	msgs := sqsTestMessages(2)
+
+	err := input.Ack(msgs[0])
+	assert.NoError(t, err)
+
+	input.Stop()
+	assert.NoError(t, <-done)
+
+	queue.AssertNumberOfCalls(t, "DeleteMessageBatch", 1)
+
+	err = input.Ack(msgs[1])
+
+	assert.NoError(t, err, "messages acknowledged after the stop should be deleted directly")
+	queue.AssertExpectations(t)
+}
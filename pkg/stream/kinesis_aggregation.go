@@ -0,0 +1,253 @@
+package stream
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+)
+
+// The aggregation format of the kinesis producer library (KPL): the magic number, followed by a protobuf encoded
+// AggregatedRecord and the md5 checksum of the protobuf message.
+//
+//	message AggregatedRecord {
+//	  repeated string partition_key_table     = 1;
+//	  repeated string explicit_hash_key_table = 2;
+//	  repeated Record records                 = 3;
+//	}
+//
+//	message Record {
+//	  required uint64 partition_key_index     = 1;
+//	  optional uint64 explicit_hash_key_index = 2;
+//	  required bytes  data                    = 3;
+//	  repeated Tag    tags                    = 4;
+//	}
+var kinesisAggregationMagic = []byte{0xF3, 0x89, 0x9A, 0xC2}
+
+const (
+	kinesisAggregationOverhead = 4 + md5.Size
+
+	protobufWireVarint  = 0
+	protobufWireFixed64 = 1
+	protobufWireBytes   = 2
+	protobufWireFixed32 = 5
+)
+
+type kinesisRecord struct {
+	partitionKey string
+	data         []byte
+}
+
+// kinesisAggregator collects records of the same partition key into one aggregated record
+type kinesisAggregator struct {
+	partitionKey string
+	records      [][]byte
+	size         int
+}
+
+func newKinesisAggregator(partitionKey string) *kinesisAggregator {
+	return &kinesisAggregator{
+		partitionKey: partitionKey,
+		records:      make([][]byte, 0),
+		size:         kinesisAggregationOverhead + protobufBytesFieldSize(len(partitionKey)),
+	}
+}
+
+func (a *kinesisAggregator) recordSize(data []byte) int {
+	return protobufBytesFieldSize(protobufVarintFieldSize(0) + protobufBytesFieldSize(len(data)))
+}
+
+// fits reports whether the data can be added without the aggregated record exceeding maxBytes.
+// An empty aggregator accepts every record, so records bigger than maxBytes are passed on unaggregated.
+func (a *kinesisAggregator) fits(data []byte, maxBytes int) bool {
+	return len(a.records) == 0 || a.size+a.recordSize(data) <= maxBytes
+}
+
+func (a *kinesisAggregator) add(data []byte) {
+	a.records = append(a.records, data)
+	a.size += a.recordSize(data)
+}
+
+func (a *kinesisAggregator) record() kinesisRecord {
+	if len(a.records) == 1 {
+		return kinesisRecord{
+			partitionKey: a.partitionKey,
+			data:         a.records[0],
+		}
+	}
+
+	body := make([]byte, 0, a.size)
+	body = appendProtobufBytes(body, 1, []byte(a.partitionKey))
+
+	for _, data := range a.records {
+		record := make([]byte, 0, a.recordSize(data))
+		record = appendProtobufVarint(record, 1, 0)
+		record = appendProtobufBytes(record, 3, data)
+
+		body = appendProtobufBytes(body, 3, record)
+	}
+
+	checksum := md5.Sum(body)
+
+	data := make([]byte, 0, len(kinesisAggregationMagic)+len(body)+len(checksum))
+	data = append(data, kinesisAggregationMagic...)
+	data = append(data, body...)
+	data = append(data, checksum[:]...)
+
+	return kinesisRecord{
+		partitionKey: a.partitionKey,
+		data:         data,
+	}
+}
+
+// aggregateKinesisRecords aggregates the records by partition key while keeping their order per partition key
+func aggregateKinesisRecords(records []kinesisRecord, maxBytes int) []kinesisRecord {
+	aggregated := make([]kinesisRecord, 0)
+	aggregators := make(map[string]*kinesisAggregator)
+	order := make([]string, 0)
+
+	for _, record := range records {
+		aggregator, ok := aggregators[record.partitionKey]
+
+		if !ok {
+			aggregator = newKinesisAggregator(record.partitionKey)
+			aggregators[record.partitionKey] = aggregator
+			order = append(order, record.partitionKey)
+		}
+
+		if !aggregator.fits(record.data, maxBytes) {
+			aggregated = append(aggregated, aggregator.record())
+
+			aggregator = newKinesisAggregator(record.partitionKey)
+			aggregators[record.partitionKey] = aggregator
+		}
+
+		aggregator.add(record.data)
+	}
+
+	for _, partitionKey := range order {
+		aggregated = append(aggregated, aggregators[partitionKey].record())
+	}
+
+	return aggregated
+}
+
+func isAggregatedKinesisRecord(data []byte) bool {
+	return len(data) > kinesisAggregationOverhead && bytes.HasPrefix(data, kinesisAggregationMagic)
+}
+
+// deaggregateKinesisRecord returns the data of all records contained in an aggregated record
+func deaggregateKinesisRecord(data []byte) ([][]byte, error) {
+	body := data[len(kinesisAggregationMagic) : len(data)-md5.Size]
+	checksum := md5.Sum(body)
+
+	if !bytes.Equal(checksum[:], data[len(data)-md5.Size:]) {
+		return nil, fmt.Errorf("the checksum of the aggregated record does not match")
+	}
+
+	records := make([][]byte, 0)
+
+	err := readProtobufFields(body, func(field uint64, value []byte) error {
+		if field != 3 {
+			return nil
+		}
+
+		return readProtobufFields(value, func(field uint64, value []byte) error {
+			if field == 3 {
+				records = append(records, value)
+			}
+
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func protobufVarintFieldSize(value uint64) int {
+	buf := make([]byte, binary.MaxVarintLen64)
+
+	return 1 + binary.PutUvarint(buf, value)
+}
+
+func protobufBytesFieldSize(length int) int {
+	return protobufVarintFieldSize(uint64(length)) + length
+}
+
+func appendProtobufVarint(buf []byte, field uint64, value uint64) []byte {
+	buf = appendUvarint(buf, field<<3|protobufWireVarint)
+
+	return appendUvarint(buf, value)
+}
+
+func appendProtobufBytes(buf []byte, field uint64, value []byte) []byte {
+	buf = appendUvarint(buf, field<<3|protobufWireBytes)
+	buf = appendUvarint(buf, uint64(len(value)))
+
+	return append(buf, value...)
+}
+
+func appendUvarint(buf []byte, value uint64) []byte {
+	varint := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(varint, value)
+
+	return append(buf, varint[:n]...)
+}
+
+// readProtobufFields calls the callback with the content of every length delimited field and skips all others
+func readProtobufFields(buf []byte, callback func(field uint64, value []byte) error) error {
+	for len(buf) > 0 {
+		key, n := binary.Uvarint(buf)
+
+		if n <= 0 {
+			return fmt.Errorf("invalid protobuf field key")
+		}
+
+		buf = buf[n:]
+		field, wireType := key>>3, key&0x7
+
+		switch wireType {
+		case protobufWireVarint:
+			if _, n = binary.Uvarint(buf); n <= 0 {
+				return fmt.Errorf("invalid protobuf varint of field %d", field)
+			}
+
+			buf = buf[n:]
+
+		case protobufWireFixed64, protobufWireFixed32:
+			size := 8
+			if wireType == protobufWireFixed32 {
+				size = 4
+			}
+
+			if len(buf) < size {
+				return fmt.Errorf("invalid protobuf fixed value of field %d", field)
+			}
+
+			buf = buf[size:]
+
+		case protobufWireBytes:
+			length, n := binary.Uvarint(buf)
+
+			if n <= 0 || uint64(len(buf)-n) < length {
+				return fmt.Errorf("invalid protobuf length of field %d", field)
+			}
+
+			value := buf[n : n+int(length)]
+			buf = buf[n+int(length):]
+
+			if err := callback(field, value); err != nil {
+				return err
+			}
+
+		default:
+			return fmt.Errorf("unsupported protobuf wire type %d of field %d", wireType, field)
+		}
+	}
+
+	return nil
+}
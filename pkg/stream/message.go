@@ -61,9 +61,12 @@ func CreateMessage(ctx context.Context, body interface{}) (*Message, error) {
 func CreateMessageFromContext(ctx context.Context) *Message {
 	span := tracing.GetSpan(ctx)
 
+	attributes := make(map[string]interface{})
+	setProducedAt(attributes)
+
 	return &Message{
 		Trace:      span.GetTrace(),
-		Attributes: make(map[string]interface{}),
+		Attributes: attributes,
 	}
 }
 
@@ -73,6 +76,8 @@ type MessageBuilder struct {
 	trace      *tracing.Trace
 	attributes map[string]interface{}
 	body       string
+	bodyValue  interface{}
+	hasBody    bool
 }
 
 func NewMessageBuilder() *MessageBuilder {
@@ -95,15 +100,22 @@ func (b *MessageBuilder) WithContext(ctx context.Context) *MessageBuilder {
 	return b
 }
 
+// WithBody sets the body which gets encoded according to the encoding and compression of the message on GetMessage
 func (b *MessageBuilder) WithBody(body interface{}) *MessageBuilder {
-	serialized, err := json.Marshal(body)
+	b.bodyValue = body
+	b.hasBody = true
 
-	if err != nil {
-		b.error = multierror.Append(b.error, err)
-		return b
-	}
+	return b
+}
+
+func (b *MessageBuilder) WithEncoding(encoding string) *MessageBuilder {
+	b.attributes[AttributeEncoding] = encoding
+
+	return b
+}
 
-	b.body = string(serialized)
+func (b *MessageBuilder) WithCompression(compression string) *MessageBuilder {
+	b.attributes[AttributeCompression] = compression
 
 	return b
 }
@@ -121,10 +133,23 @@ func (b *MessageBuilder) WithSqsMessageGroupId(groupId string) *MessageBuilder {
 }
 
 func (b *MessageBuilder) GetMessage() (*Message, error) {
+	if b.hasBody {
+		encoding, compression := getBodyEncoding(b.attributes)
+		body, err := EncodeBody(b.bodyValue, encoding, compression)
+
+		if err != nil {
+			b.error = multierror.Append(b.error, err)
+		}
+
+		b.body = body
+	}
+
 	if b.error != nil {
 		return nil, b.error
 	}
 
+	setProducedAt(b.attributes)
+
 	msg := &Message{
 		Trace:      b.trace,
 		Attributes: b.attributes,
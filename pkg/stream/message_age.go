@@ -0,0 +1,55 @@
+package stream
+
+import (
+	"github.com/applike/gosoline/pkg/mon"
+	"time"
+)
+
+// AttributeProducedAt is the time in unix milliseconds the message was created by the producer
+const AttributeProducedAt = "producedAt"
+
+const metricNameStreamMessageAge = "StreamMessageAge"
+
+func setProducedAt(attributes map[string]interface{}) {
+	if _, ok := attributes[AttributeProducedAt]; ok {
+		return
+	}
+
+	attributes[AttributeProducedAt] = time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+// GetMessageAge returns the time since the producer created the message, ok is false if the message has no producer timestamp
+func GetMessageAge(msg *Message) (age time.Duration, ok bool) {
+	var producedAt int64
+
+	// the timestamp is a float64 after the message was decoded from json
+	switch v := msg.Attributes[AttributeProducedAt].(type) {
+	case int64:
+		producedAt = v
+	case int:
+		producedAt = int64(v)
+	case float64:
+		producedAt = int64(v)
+	default:
+		return 0, false
+	}
+
+	return time.Since(time.Unix(0, producedAt*int64(time.Millisecond))), true
+}
+
+func writeMessageAgeMetric(mw mon.MetricWriter, input string, msg *Message) {
+	age, ok := GetMessageAge(msg)
+
+	if !ok {
+		return
+	}
+
+	mw.WriteOne(&mon.MetricDatum{
+		MetricName: metricNameStreamMessageAge,
+		Dimensions: map[string]string{
+			"Input": input,
+		},
+		Unit:  mon.UnitMilliseconds,
+		Value: float64(age.Nanoseconds()) / float64(time.Millisecond),
+	})
+}
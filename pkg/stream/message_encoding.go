@@ -0,0 +1,282 @@
+package stream
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding"
+	"encoding/base64"
+	"fmt"
+	"github.com/applike/gosoline/pkg/encoding/json"
+	"github.com/applike/gosoline/pkg/encoding/msgpack"
+	"github.com/golang/snappy"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"sync"
+)
+
+const (
+	AttributeEncoding    = "encoding"
+	AttributeCompression = "compression"
+)
+
+const (
+	EncodingJson    = "json"
+	EncodingMsgPack = "msgpack"
+	EncodingBinary  = "binary"
+)
+
+const (
+	CompressionNone   = "none"
+	CompressionGzip   = "gzip"
+	CompressionSnappy = "snappy"
+)
+
+// BodyEncoder converts the body of a message from and to its serialized form
+type BodyEncoder interface {
+	Encode(body interface{}) ([]byte, error)
+	Decode(data []byte, out interface{}) error
+}
+
+var bodyEncoders = struct {
+	sync.Mutex
+	encoders map[string]BodyEncoder
+}{
+	encoders: make(map[string]BodyEncoder),
+}
+
+func init() {
+	RegisterBodyEncoder(EncodingJson, jsonEncoder{})
+	RegisterBodyEncoder(EncodingMsgPack, msgPackEncoder{})
+	RegisterBodyEncoder(EncodingBinary, binaryEncoder{})
+}
+
+// RegisterBodyEncoder makes an encoding available for the encoding attribute of messages.
+// Registering an encoder for an already known encoding replaces the existing one.
+func RegisterBodyEncoder(encoding string, encoder BodyEncoder) {
+	bodyEncoders.Lock()
+	defer bodyEncoders.Unlock()
+
+	bodyEncoders.encoders[encoding] = encoder
+}
+
+func getBodyEncoder(encoding string) (BodyEncoder, error) {
+	bodyEncoders.Lock()
+	defer bodyEncoders.Unlock()
+
+	encoder, ok := bodyEncoders.encoders[encoding]
+
+	if !ok {
+		return nil, fmt.Errorf("there is no body encoder for the encoding %s", encoding)
+	}
+
+	return encoder, nil
+}
+
+// getBodyEncoding returns the encoding and compression of the attributes, messages without them are plain json
+func getBodyEncoding(attributes map[string]interface{}) (string, string) {
+	encoding, _ := attributes[AttributeEncoding].(string)
+	compression, _ := attributes[AttributeCompression].(string)
+
+	return withDefaultBodyEncoding(encoding, compression)
+}
+
+func withDefaultBodyEncoding(encoding string, compression string) (string, string) {
+	if encoding == "" {
+		encoding = EncodingJson
+	}
+
+	if compression == "" {
+		compression = CompressionNone
+	}
+
+	return encoding, compression
+}
+
+// EncodeBody serializes and compresses the body. Everything except uncompressed json is base64 encoded,
+// as the body of a message has to be text for most of the transports.
+func EncodeBody(body interface{}, encoding string, compression string) (string, error) {
+	encoding, compression = withDefaultBodyEncoding(encoding, compression)
+	encoder, err := getBodyEncoder(encoding)
+
+	if err != nil {
+		return "", err
+	}
+
+	data, err := encoder.Encode(body)
+
+	if err != nil {
+		return "", err
+	}
+
+	if data, err = compress(data, compression); err != nil {
+		return "", err
+	}
+
+	if encoding == EncodingJson && compression == CompressionNone {
+		return string(data), nil
+	}
+
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+func DecodeBody(body string, encoding string, compression string, out interface{}) error {
+	encoding, compression = withDefaultBodyEncoding(encoding, compression)
+	data, err := decodeBodyData(body, encoding, compression)
+
+	if err != nil {
+		return err
+	}
+
+	encoder, err := getBodyEncoder(encoding)
+
+	if err != nil {
+		return err
+	}
+
+	return encoder.Decode(data, out)
+}
+
+// decodeBodyUseNumber decodes json bodies with json.Number instead of float64, so integers above 2^53 survive
+// the decoding into interfaces. The other encodings keep the types of numbers anyway.
+func decodeBodyUseNumber(body string, encoding string, compression string, out interface{}) error {
+	encoding, compression = withDefaultBodyEncoding(encoding, compression)
+
+	if encoding != EncodingJson {
+		return DecodeBody(body, encoding, compression, out)
+	}
+
+	data, err := decodeBodyData(body, encoding, compression)
+
+	if err != nil {
+		return err
+	}
+
+	return json.UnmarshalUseNumber(data, out)
+}
+
+func decodeBodyData(body string, encoding string, compression string) ([]byte, error) {
+	var err error
+	data := []byte(body)
+
+	if encoding != EncodingJson || compression != CompressionNone {
+		if data, err = base64.StdEncoding.DecodeString(body); err != nil {
+			return nil, errors.Wrap(err, "can not decode the base64 body")
+		}
+	}
+
+	return decompress(data, compression)
+}
+
+// DecodeBody decodes the body of the message according to its encoding and compression attributes
+func (m *Message) DecodeBody(out interface{}) error {
+	encoding, compression := getBodyEncoding(m.Attributes)
+
+	return DecodeBody(m.Body, encoding, compression, out)
+}
+
+func compress(data []byte, compression string) ([]byte, error) {
+	switch compression {
+	case CompressionNone:
+		return data, nil
+
+	case CompressionGzip:
+		buf := &bytes.Buffer{}
+		writer := gzip.NewWriter(buf)
+
+		if _, err := writer.Write(data); err != nil {
+			return nil, err
+		}
+
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+
+	case CompressionSnappy:
+		return snappy.Encode(nil, data), nil
+
+	default:
+		return nil, fmt.Errorf("unknown compression %s", compression)
+	}
+}
+
+func decompress(data []byte, compression string) ([]byte, error) {
+	switch compression {
+	case CompressionNone:
+		return data, nil
+
+	case CompressionGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+
+		if err != nil {
+			return nil, err
+		}
+
+		defer reader.Close()
+
+		return ioutil.ReadAll(reader)
+
+	case CompressionSnappy:
+		return snappy.Decode(nil, data)
+
+	default:
+		return nil, fmt.Errorf("unknown compression %s", compression)
+	}
+}
+
+type jsonEncoder struct{}
+
+func (e jsonEncoder) Encode(body interface{}) ([]byte, error) {
+	return json.Marshal(body)
+}
+
+func (e jsonEncoder) Decode(data []byte, out interface{}) error {
+	return json.Unmarshal(data, out)
+}
+
+type msgPackEncoder struct{}
+
+func (e msgPackEncoder) Encode(body interface{}) ([]byte, error) {
+	return msgpack.Marshal(body)
+}
+
+func (e msgPackEncoder) Decode(data []byte, out interface{}) error {
+	return msgpack.Unmarshal(data, out)
+}
+
+// protoMessage is implemented by the generated messages of gogo/protobuf
+type protoMessage interface {
+	Marshal() ([]byte, error)
+	Unmarshal(data []byte) error
+}
+
+// binaryEncoder handles raw bytes, encoding.BinaryMarshaler and protobuf style messages
+type binaryEncoder struct{}
+
+func (e binaryEncoder) Encode(body interface{}) ([]byte, error) {
+	switch b := body.(type) {
+	case []byte:
+		return b, nil
+	case encoding.BinaryMarshaler:
+		return b.MarshalBinary()
+	case protoMessage:
+		return b.Marshal()
+	default:
+		return nil, fmt.Errorf("the body of type %T can not be encoded as binary", body)
+	}
+}
+
+func (e binaryEncoder) Decode(data []byte, out interface{}) error {
+	switch o := out.(type) {
+	case *[]byte:
+		*o = data
+		return nil
+	case encoding.BinaryUnmarshaler:
+		return o.UnmarshalBinary(data)
+	case protoMessage:
+		return o.Unmarshal(data)
+	default:
+		return fmt.Errorf("the body can not be decoded as binary into the type %T", out)
+	}
+}
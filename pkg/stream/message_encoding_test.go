@@ -0,0 +1,81 @@
+package stream_test
+
+import (
+	"github.com/applike/gosoline/pkg/stream"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type encodingTestBody struct {
+	Foo string `json:"foo" msgpack:"foo"`
+	Bar int    `json:"bar" msgpack:"bar"`
+}
+
+func TestMessageBuilder_Encodings(t *testing.T) {
+	encodings := []string{stream.EncodingJson, stream.EncodingMsgPack}
+	compressions := []string{stream.CompressionNone, stream.CompressionGzip, stream.CompressionSnappy}
+
+	for _, encoding := range encodings {
+		for _, compression := range compressions {
+			body := encodingTestBody{
+				Foo: "foo",
+				Bar: 42,
+			}
+
+			msg, err := stream.NewMessageBuilder().
+				WithEncoding(encoding).
+				WithCompression(compression).
+				WithBody(body).
+				GetMessage()
+			assert.NoError(t, err, "%s/%s", encoding, compression)
+
+			serialized, err := msg.MarshalToString()
+			assert.NoError(t, err, "%s/%s", encoding, compression)
+
+			received := &stream.Message{}
+			err = received.UnmarshalFromString(serialized)
+			assert.NoError(t, err, "%s/%s", encoding, compression)
+
+			decoded := encodingTestBody{}
+			err = received.DecodeBody(&decoded)
+			assert.NoError(t, err, "%s/%s", encoding, compression)
+			assert.Equal(t, body, decoded, "%s/%s", encoding, compression)
+		}
+	}
+}
+
+func TestMessageBuilder_EncodingBinary(t *testing.T) {
+	msg, err := stream.NewMessageBuilder().
+		WithEncoding(stream.EncodingBinary).
+		WithCompression(stream.CompressionGzip).
+		WithBody([]byte{0, 1, 2, 255}).
+		GetMessage()
+	assert.NoError(t, err)
+
+	decoded := make([]byte, 0)
+	err = msg.DecodeBody(&decoded)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 1, 2, 255}, decoded)
+}
+
+func TestMessage_DecodeBodyWithoutAttributes(t *testing.T) {
+	msg := &stream.Message{
+		Body: `{"foo":"foo","bar":42}`,
+	}
+
+	decoded := encodingTestBody{}
+	err := msg.DecodeBody(&decoded)
+
+	assert.NoError(t, err)
+	assert.Equal(t, encodingTestBody{Foo: "foo", Bar: 42}, decoded)
+}
+
+func TestMessageBuilder_UnknownEncoding(t *testing.T) {
+	_, err := stream.NewMessageBuilder().
+		WithEncoding("unknown").
+		WithBody("foo").
+		GetMessage()
+
+	assert.Error(t, err)
+}
@@ -3,10 +3,12 @@ package stream
 import "fmt"
 
 type ModelMsg struct {
-	CrudType string
-	Version  int
-	ModelId  string
-	Body     string
+	CrudType    string
+	Version     int
+	ModelId     string
+	Encoding    string
+	Compression string
+	Body        string
 }
 
 func CreateModelMsg(raw *Message) (*ModelMsg, error) {
@@ -38,10 +40,18 @@ func CreateModelMsg(raw *Message) (*ModelMsg, error) {
 		return nil, fmt.Errorf("modelId is not a string: %v", raw.Attributes["modelId"])
 	}
 
+	encoding, compression := getBodyEncoding(raw.Attributes)
+
 	return &ModelMsg{
-		CrudType: crudType,
-		Version:  version,
-		ModelId:  modelId,
-		Body:     raw.Body,
+		CrudType:    crudType,
+		Version:     version,
+		ModelId:     modelId,
+		Encoding:    encoding,
+		Compression: compression,
+		Body:        raw.Body,
 	}, nil
 }
+
+func (m *ModelMsg) DecodeBody(out interface{}) error {
+	return DecodeBody(m.Body, m.Encoding, m.Compression, out)
+}
@@ -0,0 +1,203 @@
+package stream
+
+import (
+	"fmt"
+	"github.com/applike/gosoline/pkg/blob"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/mdl"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/pkg/errors"
+	"github.com/twinj/uuid"
+	"sync"
+)
+
+const (
+	AttributeBlobBucket = "blobBucket"
+	AttributeBlobPrefix = "blobPrefix"
+	AttributeBlobKey    = "blobKey"
+)
+
+// sqs and sns accept up to 256KB, the remaining space is left for the attributes and the sns envelope
+const defaultOffloadThreshold = 200 * 1024
+
+// OffloadSettings configure the claim check mode of the sqs and sns inputs and outputs. The messages are written
+// and read by the blob.BatchRunner, so it has to be added to the kernel of the application.
+type OffloadSettings struct {
+	Enabled bool `cfg:"enabled"`
+	// Threshold is the size in bytes of a serialized message above which its body is written to the blob store
+	Threshold int    `cfg:"threshold"`
+	Bucket    string `cfg:"bucket"`
+	Prefix    string `cfg:"prefix"`
+	// Cleanup deletes the blob of a message after the message was acknowledged by the input
+	Cleanup bool `cfg:"cleanup"`
+}
+
+type BlobStoreFactory func(bucket string, prefix string) blob.Store
+
+// MessageOffloader writes the bodies of large messages to the blob store and only sends a pointer to them.
+// The bucket and prefix are part of the pointer, so an input is able to read blobs written by other applications.
+type MessageOffloader struct {
+	lck      sync.Mutex
+	factory  BlobStoreFactory
+	stores   map[string]blob.Store
+	settings OffloadSettings
+}
+
+func NewMessageOffloader(config cfg.Config, logger mon.Logger, settings OffloadSettings) *MessageOffloader {
+	appId := cfg.GetAppIdFromConfig(config)
+
+	if settings.Bucket == "" {
+		settings.Bucket = fmt.Sprintf("%s-%s-%s", appId.Project, appId.Environment, appId.Family)
+	}
+
+	if settings.Prefix == "" {
+		settings.Prefix = fmt.Sprintf("%s/stream", appId.Application)
+	}
+
+	runner := blob.ProvideBatchRunner()
+	client := blob.ProvideS3Client(config)
+
+	factory := func(bucket string, prefix string) blob.Store {
+		return blob.NewStoreWithInterfaces(logger, runner, client, blob.Settings{
+			Bucket: bucket,
+			Prefix: prefix,
+		})
+	}
+
+	return NewMessageOffloaderWithInterfaces(factory, settings)
+}
+
+func NewMessageOffloaderWithInterfaces(factory BlobStoreFactory, settings OffloadSettings) *MessageOffloader {
+	if settings.Threshold <= 0 {
+		settings.Threshold = defaultOffloadThreshold
+	}
+
+	return &MessageOffloader{
+		factory:  factory,
+		stores:   make(map[string]blob.Store),
+		settings: settings,
+	}
+}
+
+// MarshalToString serializes the message. If the result exceeds the threshold, the body is written to the
+// blob store and a copy of the message pointing to the blob is serialized instead.
+func (o *MessageOffloader) MarshalToString(msg *Message) (string, error) {
+	serialized, err := msg.MarshalToString()
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(serialized) <= o.settings.Threshold {
+		return serialized, nil
+	}
+
+	key := uuid.NewV4().String()
+	store := o.getStore(o.settings.Bucket, o.settings.Prefix)
+
+	err = store.WriteOne(&blob.Object{
+		Key:  mdl.String(key),
+		Body: []byte(msg.Body),
+	})
+
+	if err != nil {
+		return "", errors.Wrap(err, "could not write the body of the message to the blob store")
+	}
+
+	attributes := make(map[string]interface{}, len(msg.Attributes)+3)
+
+	for k, v := range msg.Attributes {
+		attributes[k] = v
+	}
+
+	attributes[AttributeBlobBucket] = o.settings.Bucket
+	attributes[AttributeBlobPrefix] = o.settings.Prefix
+	attributes[AttributeBlobKey] = key
+
+	pointer := &Message{
+		Trace:      msg.Trace,
+		Attributes: attributes,
+	}
+
+	return pointer.MarshalToString()
+}
+
+// Restore reads the body of a message pointing to the blob store. Other messages are left untouched.
+func (o *MessageOffloader) Restore(msg *Message) error {
+	store, obj, ok, err := o.getBlob(msg)
+
+	if !ok || err != nil {
+		return err
+	}
+
+	if err = store.ReadOne(obj); err != nil {
+		return errors.Wrap(err, "could not read the body of the message from the blob store")
+	}
+
+	if !obj.Exists {
+		return fmt.Errorf("the body of the message does not exist in the blob store with key %s", *obj.Key)
+	}
+
+	msg.Body = string(obj.Body)
+
+	return nil
+}
+
+// Cleanup deletes the blob of an acknowledged message if the cleanup is enabled
+func (o *MessageOffloader) Cleanup(msg *Message) error {
+	if !o.settings.Cleanup {
+		return nil
+	}
+
+	store, obj, ok, err := o.getBlob(msg)
+
+	if !ok || err != nil {
+		return err
+	}
+
+	if err = store.DeleteOne(obj); err != nil {
+		return errors.Wrap(err, "could not delete the body of the message from the blob store")
+	}
+
+	return nil
+}
+
+func (o *MessageOffloader) getBlob(msg *Message) (blob.Store, *blob.Object, bool, error) {
+	if _, ok := msg.Attributes[AttributeBlobKey]; !ok {
+		return nil, nil, false, nil
+	}
+
+	values := make(map[string]string, 3)
+
+	for _, attribute := range []string{AttributeBlobBucket, AttributeBlobPrefix, AttributeBlobKey} {
+		value, ok := msg.Attributes[attribute].(string)
+
+		if !ok {
+			return nil, nil, false, fmt.Errorf("the attribute %s of the message should be string but instead is %T", attribute, msg.Attributes[attribute])
+		}
+
+		values[attribute] = value
+	}
+
+	store := o.getStore(values[AttributeBlobBucket], values[AttributeBlobPrefix])
+	obj := &blob.Object{
+		Key: mdl.String(values[AttributeBlobKey]),
+	}
+
+	return store, obj, true, nil
+}
+
+func (o *MessageOffloader) getStore(bucket string, prefix string) blob.Store {
+	o.lck.Lock()
+	defer o.lck.Unlock()
+
+	key := fmt.Sprintf("%s/%s", bucket, prefix)
+
+	if store, ok := o.stores[key]; ok {
+		return store
+	}
+
+	o.stores[key] = o.factory(bucket, prefix)
+
+	return o.stores[key]
+}
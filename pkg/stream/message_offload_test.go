@@ -0,0 +1,102 @@
+package stream_test
+
+import (
+	"github.com/applike/gosoline/pkg/blob"
+	blobMocks "github.com/applike/gosoline/pkg/blob/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+)
+
+func newTestOffloader(store blob.Store, cleanup bool) *stream.MessageOffloader {
+	factory := func(bucket string, prefix string) blob.Store {
+		return store
+	}
+
+	return stream.NewMessageOffloaderWithInterfaces(factory, stream.OffloadSettings{
+		Enabled:   true,
+		Threshold: 100,
+		Bucket:    "bucket",
+		Prefix:    "prefix",
+		Cleanup:   cleanup,
+	})
+}
+
+func TestMessageOffloader_MarshalToStringSmall(t *testing.T) {
+	store := new(blobMocks.Store)
+	offloader := newTestOffloader(store, false)
+
+	msg := &stream.Message{
+		Body: "foobar",
+	}
+
+	serialized, err := offloader.MarshalToString(msg)
+	assert.NoError(t, err)
+
+	expected, _ := msg.MarshalToString()
+	assert.Equal(t, expected, serialized, "small messages should not be offloaded")
+	store.AssertExpectations(t)
+}
+
+func TestMessageOffloader_OffloadAndRestore(t *testing.T) {
+	body := strings.Repeat("a", 200)
+	var written []byte
+
+	store := new(blobMocks.Store)
+	store.On("WriteOne", mock.AnythingOfType("*blob.Object")).Run(func(args mock.Arguments) {
+		written = args.Get(0).(*blob.Object).Body
+	}).Return(nil).Once()
+	store.On("ReadOne", mock.AnythingOfType("*blob.Object")).Run(func(args mock.Arguments) {
+		obj := args.Get(0).(*blob.Object)
+		obj.Body = written
+		obj.Exists = true
+	}).Return(nil).Once()
+	store.On("DeleteOne", mock.AnythingOfType("*blob.Object")).Return(nil).Once()
+
+	offloader := newTestOffloader(store, true)
+
+	serialized, err := offloader.MarshalToString(&stream.Message{
+		Attributes: map[string]interface{}{
+			"foo": "bar",
+		},
+		Body: body,
+	})
+	assert.NoError(t, err)
+	assert.True(t, len(serialized) < 200, "the body should not be part of the serialized message")
+
+	msg := &stream.Message{}
+	err = msg.UnmarshalFromString(serialized)
+	assert.NoError(t, err)
+	assert.Equal(t, "", msg.Body)
+	assert.Equal(t, "bar", msg.Attributes["foo"])
+	assert.Equal(t, "bucket", msg.Attributes[stream.AttributeBlobBucket])
+
+	err = offloader.Restore(msg)
+	assert.NoError(t, err)
+	assert.Equal(t, body, msg.Body)
+
+	err = offloader.Cleanup(msg)
+	assert.NoError(t, err)
+
+	store.AssertExpectations(t)
+}
+
+func TestMessageOffloader_RestoreMissing(t *testing.T) {
+	store := new(blobMocks.Store)
+	store.On("ReadOne", mock.AnythingOfType("*blob.Object")).Return(nil).Once()
+
+	offloader := newTestOffloader(store, false)
+
+	err := offloader.Restore(&stream.Message{
+		Attributes: map[string]interface{}{
+			stream.AttributeBlobBucket: "bucket",
+			stream.AttributeBlobPrefix: "prefix",
+			stream.AttributeBlobKey:    "key",
+		},
+	})
+
+	assert.Error(t, err, "a missing blob should fail the restore")
+	store.AssertExpectations(t)
+}
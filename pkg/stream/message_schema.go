@@ -0,0 +1,192 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/pkg/errors"
+	"sync"
+)
+
+const (
+	AttributeSchemaName    = "schemaName"
+	AttributeSchemaVersion = "schemaVersion"
+)
+
+const MiddlewareUpcasting = "upcasting"
+
+// Upcaster migrates the body of a message from its version to the next one. The body is decoded to its generic
+// form, so upcasters don't need the types of historical versions. Numbers of json bodies are json.Number values.
+type Upcaster func(ctx context.Context, body map[string]interface{}) (map[string]interface{}, error)
+
+var upcasters = struct {
+	sync.Mutex
+	schemas map[string]map[int]Upcaster
+}{
+	schemas: make(map[string]map[int]Upcaster),
+}
+
+// RegisterUpcaster registers the upcaster migrating the given version of the schema to the version after it.
+// Registering an upcaster for an already known version replaces the existing one.
+func RegisterUpcaster(schema string, fromVersion int, upcaster Upcaster) {
+	upcasters.Lock()
+	defer upcasters.Unlock()
+
+	if _, ok := upcasters.schemas[schema]; !ok {
+		upcasters.schemas[schema] = make(map[int]Upcaster)
+	}
+
+	upcasters.schemas[schema][fromVersion] = upcaster
+}
+
+// GetLatestSchemaVersion returns the version the registered upcasters of the schema migrate to
+func GetLatestSchemaVersion(schema string) (int, bool) {
+	upcasters.Lock()
+	defer upcasters.Unlock()
+
+	latest, found := 0, false
+
+	for fromVersion := range upcasters.schemas[schema] {
+		if !found || fromVersion+1 > latest {
+			latest, found = fromVersion+1, true
+		}
+	}
+
+	return latest, found
+}
+
+func getUpcaster(schema string, fromVersion int) (Upcaster, error) {
+	upcasters.Lock()
+	defer upcasters.Unlock()
+
+	upcaster, ok := upcasters.schemas[schema][fromVersion]
+
+	if !ok {
+		return nil, fmt.Errorf("there is no upcaster for version %d of the schema %s", fromVersion, schema)
+	}
+
+	return upcaster, nil
+}
+
+// SetSchema stamps the schema name and version of the body on the message
+func SetSchema(msg *Message, schema string, version int) {
+	msg.Attributes[AttributeSchemaName] = schema
+	msg.Attributes[AttributeSchemaVersion] = version
+}
+
+// GetSchema returns the schema name and version of the message, ok is false if the message has no schema
+func GetSchema(msg *Message) (schema string, version int, ok bool) {
+	schema, ok = msg.Attributes[AttributeSchemaName].(string)
+
+	if !ok || schema == "" {
+		return "", 0, false
+	}
+
+	// the version is a float64 after the message was decoded from json
+	switch v := msg.Attributes[AttributeSchemaVersion].(type) {
+	case int:
+		return schema, v, true
+	case int64:
+		return schema, int(v), true
+	case float64:
+		return schema, int(v), true
+	}
+
+	return "", 0, false
+}
+
+// Upcast migrates the body of the message step by step to the given version of its schema.
+// Messages without a schema or already at the given version are left untouched.
+func Upcast(ctx context.Context, msg *Message, toVersion int) error {
+	schema, version, ok := GetSchema(msg)
+
+	if !ok || version == toVersion {
+		return nil
+	}
+
+	encoding, compression := getBodyEncoding(msg.Attributes)
+	body, err := UpcastBody(ctx, schema, msg.Body, encoding, compression, version, toVersion)
+
+	if err != nil {
+		return err
+	}
+
+	msg.Body = body
+	msg.Attributes[AttributeSchemaVersion] = toVersion
+
+	return nil
+}
+
+// UpcastToLatest migrates the body of the message to the latest version of its schema known by the registered upcasters
+func UpcastToLatest(ctx context.Context, msg *Message) error {
+	schema, _, ok := GetSchema(msg)
+
+	if !ok {
+		return nil
+	}
+
+	latest, ok := GetLatestSchemaVersion(schema)
+
+	if !ok {
+		return nil
+	}
+
+	return Upcast(ctx, msg, latest)
+}
+
+// UpcastBody decodes the encoded body, applies all upcasters from the version up to the target version and encodes it again
+func UpcastBody(ctx context.Context, schema string, body string, encoding string, compression string, fromVersion int, toVersion int) (string, error) {
+	if fromVersion > toVersion {
+		return "", fmt.Errorf("can not downcast the schema %s from version %d to %d", schema, fromVersion, toVersion)
+	}
+
+	if fromVersion == toVersion {
+		return body, nil
+	}
+
+	decoded := make(map[string]interface{})
+
+	if err := decodeBodyUseNumber(body, encoding, compression, &decoded); err != nil {
+		return "", errors.Wrapf(err, "can not decode the body of version %d of the schema %s", fromVersion, schema)
+	}
+
+	for version := fromVersion; version < toVersion; version++ {
+		upcaster, err := getUpcaster(schema, version)
+
+		if err != nil {
+			return "", err
+		}
+
+		if decoded, err = upcaster(ctx, decoded); err != nil {
+			return "", errors.Wrapf(err, "can not upcast version %d of the schema %s", version, schema)
+		}
+	}
+
+	return EncodeBody(decoded, encoding, compression)
+}
+
+func (b *MessageBuilder) WithSchema(schema string, version int) *MessageBuilder {
+	b.attributes[AttributeSchemaName] = schema
+	b.attributes[AttributeSchemaVersion] = version
+
+	return b
+}
+
+// NewUpcastingMiddleware migrates the messages to the latest version of their schema before they are consumed.
+// Messages which can't be upcasted fail like any other consume error.
+func NewUpcastingMiddleware() ConsumerMiddleware {
+	return func(next ConsumeHandler) ConsumeHandler {
+		return func(ctx context.Context, msg *Message) (bool, error) {
+			if err := UpcastToLatest(ctx, msg); err != nil {
+				return false, err
+			}
+
+			return next(ctx, msg)
+		}
+	}
+}
+
+func newUpcastingMiddlewareFromConfig(_ cfg.Config, _ mon.Logger, _ string) ConsumerMiddleware {
+	return NewUpcastingMiddleware()
+}
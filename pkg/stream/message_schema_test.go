@@ -0,0 +1,92 @@
+package stream_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/stream"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func init() {
+	stream.RegisterUpcaster("user", 1, func(ctx context.Context, body map[string]interface{}) (map[string]interface{}, error) {
+		body["fullName"] = body["name"]
+		delete(body, "name")
+
+		return body, nil
+	})
+
+	stream.RegisterUpcaster("user", 2, func(ctx context.Context, body map[string]interface{}) (map[string]interface{}, error) {
+		body["active"] = true
+
+		return body, nil
+	})
+
+	stream.RegisterUpcaster("broken", 1, func(ctx context.Context, body map[string]interface{}) (map[string]interface{}, error) {
+		return nil, fmt.Errorf("upcast failed")
+	})
+}
+
+func TestUpcastToLatest(t *testing.T) {
+	msg, err := stream.NewMessageBuilder().WithSchema("user", 1).WithBody(map[string]interface{}{
+		"name": "john",
+	}).GetMessage()
+	assert.NoError(t, err)
+
+	err = stream.UpcastToLatest(context.Background(), msg)
+	assert.NoError(t, err)
+
+	body := make(map[string]interface{})
+	err = msg.DecodeBody(&body)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"fullName": "john", "active": true}, body, "all upcasters should be applied in order")
+
+	_, version, ok := stream.GetSchema(msg)
+	assert.True(t, ok)
+	assert.Equal(t, 3, version)
+}
+
+func TestUpcast_KeepsLargeIntegers(t *testing.T) {
+	msg := &stream.Message{
+		Attributes: map[string]interface{}{
+			stream.AttributeSchemaName:    "user",
+			stream.AttributeSchemaVersion: 2,
+		},
+		Body: `{"id":9007199254740993}`,
+	}
+
+	err := stream.Upcast(context.Background(), msg, 3)
+
+	assert.NoError(t, err)
+	assert.Contains(t, msg.Body, `"id":9007199254740993`, "integers above 2^53 should not lose precision")
+}
+
+func TestUpcast_Errors(t *testing.T) {
+	msg, err := stream.NewMessageBuilder().WithSchema("user", 3).WithBody(map[string]interface{}{}).GetMessage()
+	assert.NoError(t, err)
+
+	err = stream.Upcast(context.Background(), msg, 2)
+	assert.EqualError(t, err, "can not downcast the schema user from version 3 to 2")
+
+	err = stream.Upcast(context.Background(), msg, 4)
+	assert.EqualError(t, err, "there is no upcaster for version 3 of the schema user")
+
+	msg, err = stream.NewMessageBuilder().WithSchema("broken", 1).WithBody(map[string]interface{}{}).GetMessage()
+	assert.NoError(t, err)
+
+	err = stream.UpcastToLatest(context.Background(), msg)
+	assert.EqualError(t, err, "can not upcast version 1 of the schema broken: upcast failed")
+}
+
+func TestUpcast_WithoutSchema(t *testing.T) {
+	msg := &stream.Message{
+		Attributes: map[string]interface{}{},
+		Body:       `{"name":"john"}`,
+	}
+
+	err := stream.UpcastToLatest(context.Background(), msg)
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"john"}`, msg.Body, "messages without schema should not be changed")
+}
@@ -1,9 +1,11 @@
 package stream_test
 
 import (
+	"fmt"
 	"github.com/applike/gosoline/pkg/stream"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestMessageBuilder_GetMessage(t *testing.T) {
@@ -29,3 +31,31 @@ func BuildSqsTestMessage() (*stream.Message, error) {
 
 	return builder.GetMessage()
 }
+
+func TestMessageBuilder_ProducedAt(t *testing.T) {
+	msg, err := BuildSqsTestMessage()
+
+	assert.NoError(t, err)
+	assert.Contains(t, msg.Attributes, stream.AttributeProducedAt)
+
+	age, ok := stream.GetMessageAge(msg)
+
+	assert.True(t, ok)
+	assert.True(t, age >= 0 && age < time.Minute, "the message should have been produced just now")
+}
+
+func TestGetMessageAge(t *testing.T) {
+	producedAt := time.Now().Add(-time.Hour).UnixNano() / int64(time.Millisecond)
+
+	msg := &stream.Message{}
+	err := msg.UnmarshalFromString(fmt.Sprintf(`{"attributes":{"producedAt":%d},"body":"foo"}`, producedAt))
+	assert.NoError(t, err)
+
+	age, ok := stream.GetMessageAge(msg)
+
+	assert.True(t, ok)
+	assert.InDelta(t, time.Hour.Seconds(), age.Seconds(), 5)
+
+	_, ok = stream.GetMessageAge(&stream.Message{})
+	assert.False(t, ok, "messages without a producer timestamp have no age")
+}
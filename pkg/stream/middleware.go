@@ -0,0 +1,204 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/mon"
+	"sync"
+)
+
+const (
+	MiddlewareLogging  = "logging"
+	MiddlewareMetrics  = "metrics"
+	MiddlewareRecovery = "recovery"
+)
+
+type ConsumeHandler func(ctx context.Context, msg *Message) (bool, error)
+type ConsumerMiddleware func(next ConsumeHandler) ConsumeHandler
+
+type ProcessHandler func(ctx context.Context, messages []*Message) ([]*Message, error)
+type PipelineMiddleware func(next ProcessHandler) ProcessHandler
+
+type WriteHandler func(ctx context.Context, batch []*Message) error
+type OutputMiddleware func(next WriteHandler) WriteHandler
+
+// The factories get the name of the input or output the middleware is configured for
+type ConsumerMiddlewareFactory func(config cfg.Config, logger mon.Logger, name string) ConsumerMiddleware
+type PipelineMiddlewareFactory func(config cfg.Config, logger mon.Logger, name string) PipelineMiddleware
+type OutputMiddlewareFactory func(config cfg.Config, logger mon.Logger, name string) OutputMiddleware
+
+var middlewareFactories = struct {
+	sync.Mutex
+	consumer map[string]ConsumerMiddlewareFactory
+	pipeline map[string]PipelineMiddlewareFactory
+	output   map[string]OutputMiddlewareFactory
+}{
+	consumer: make(map[string]ConsumerMiddlewareFactory),
+	pipeline: make(map[string]PipelineMiddlewareFactory),
+	output:   make(map[string]OutputMiddlewareFactory),
+}
+
+func init() {
+	RegisterConsumerMiddlewareFactory(MiddlewareLogging, newConsumerLoggingMiddlewareFromConfig)
+	RegisterConsumerMiddlewareFactory(MiddlewareMetrics, newConsumerMetricMiddlewareFromConfig)
+	RegisterConsumerMiddlewareFactory(MiddlewareRecovery, newConsumerRecoveryMiddlewareFromConfig)
+	RegisterConsumerMiddlewareFactory(MiddlewareDeduplication, newDeduplicationMiddlewareFromConfig)
+	RegisterConsumerMiddlewareFactory(MiddlewareUpcasting, newUpcastingMiddlewareFromConfig)
+
+	RegisterPipelineMiddlewareFactory(MiddlewareLogging, newPipelineLoggingMiddlewareFromConfig)
+	RegisterPipelineMiddlewareFactory(MiddlewareMetrics, newPipelineMetricMiddlewareFromConfig)
+	RegisterPipelineMiddlewareFactory(MiddlewareRecovery, newPipelineRecoveryMiddlewareFromConfig)
+
+	RegisterOutputMiddlewareFactory(MiddlewareLogging, newOutputLoggingMiddlewareFromConfig)
+	RegisterOutputMiddlewareFactory(MiddlewareMetrics, newOutputMetricMiddlewareFromConfig)
+	RegisterOutputMiddlewareFactory(MiddlewareRecovery, newOutputRecoveryMiddlewareFromConfig)
+}
+
+// RegisterConsumerMiddlewareFactory makes a middleware available for input_<name>_middlewares of consumers
+func RegisterConsumerMiddlewareFactory(name string, factory ConsumerMiddlewareFactory) {
+	middlewareFactories.Lock()
+	defer middlewareFactories.Unlock()
+
+	middlewareFactories.consumer[name] = factory
+}
+
+// RegisterPipelineMiddlewareFactory makes a middleware available for input_pipeline_middlewares
+func RegisterPipelineMiddlewareFactory(name string, factory PipelineMiddlewareFactory) {
+	middlewareFactories.Lock()
+	defer middlewareFactories.Unlock()
+
+	middlewareFactories.pipeline[name] = factory
+}
+
+// RegisterOutputMiddlewareFactory makes a middleware available for output_<name>_middlewares
+func RegisterOutputMiddlewareFactory(name string, factory OutputMiddlewareFactory) {
+	middlewareFactories.Lock()
+	defer middlewareFactories.Unlock()
+
+	middlewareFactories.output[name] = factory
+}
+
+func getConfiguredMiddlewareNames(config cfg.Config, key string) []string {
+	if !config.IsSet(key) {
+		return []string{}
+	}
+
+	return config.GetStringSlice(key)
+}
+
+func readConsumerMiddlewares(config cfg.Config, logger mon.Logger, input string) []ConsumerMiddleware {
+	names := getConfiguredMiddlewareNames(config, getConfigurableInputMiddlewaresKey(input))
+	middlewares := make([]ConsumerMiddleware, 0, len(names))
+
+	for _, name := range names {
+		middlewareFactories.Lock()
+		factory, ok := middlewareFactories.consumer[name]
+		middlewareFactories.Unlock()
+
+		if !ok {
+			logger.Fatalf(fmt.Errorf("invalid consumer middleware %s", name), "invalid consumer middleware %s for input %s", name, input)
+			continue
+		}
+
+		middlewares = append(middlewares, factory(config, logger, input))
+	}
+
+	return middlewares
+}
+
+func readPipelineMiddlewares(config cfg.Config, logger mon.Logger, input string) []PipelineMiddleware {
+	names := getConfiguredMiddlewareNames(config, getConfigurableInputMiddlewaresKey(input))
+	middlewares := make([]PipelineMiddleware, 0, len(names))
+
+	for _, name := range names {
+		middlewareFactories.Lock()
+		factory, ok := middlewareFactories.pipeline[name]
+		middlewareFactories.Unlock()
+
+		if !ok {
+			logger.Fatalf(fmt.Errorf("invalid pipeline middleware %s", name), "invalid pipeline middleware %s for input %s", name, input)
+			continue
+		}
+
+		middlewares = append(middlewares, factory(config, logger, input))
+	}
+
+	return middlewares
+}
+
+func readOutputMiddlewares(config cfg.Config, logger mon.Logger, output string) []OutputMiddleware {
+	names := getConfiguredMiddlewareNames(config, getConfigurableOutputMiddlewaresKey(output))
+	middlewares := make([]OutputMiddleware, 0, len(names))
+
+	for _, name := range names {
+		middlewareFactories.Lock()
+		factory, ok := middlewareFactories.output[name]
+		middlewareFactories.Unlock()
+
+		if !ok {
+			logger.Fatalf(fmt.Errorf("invalid output middleware %s", name), "invalid output middleware %s for output %s", name, output)
+			continue
+		}
+
+		middlewares = append(middlewares, factory(config, logger, output))
+	}
+
+	return middlewares
+}
+
+// chainConsumerMiddlewares makes the first middleware the outermost one, so it is called first and returns last
+func chainConsumerMiddlewares(handler ConsumeHandler, middlewares []ConsumerMiddleware) ConsumeHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
+
+func chainPipelineMiddlewares(handler ProcessHandler, middlewares []PipelineMiddleware) ProcessHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
+
+func chainOutputMiddlewares(handler WriteHandler, middlewares []OutputMiddleware) WriteHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
+
+type middlewareOutput struct {
+	handler WriteHandler
+}
+
+// NewOutputWithMiddlewares wraps the output, so every write passes the middlewares first
+func NewOutputWithMiddlewares(output Output, middlewares ...OutputMiddleware) Output {
+	if len(middlewares) == 0 {
+		return output
+	}
+
+	return &middlewareOutput{
+		handler: chainOutputMiddlewares(output.Write, middlewares),
+	}
+}
+
+func (o *middlewareOutput) WriteOne(ctx context.Context, msg *Message) error {
+	return o.Write(ctx, []*Message{msg})
+}
+
+func (o *middlewareOutput) Write(ctx context.Context, batch []*Message) error {
+	return o.handler(ctx, batch)
+}
+
+func getConfigurableInputMiddlewaresKey(name string) string {
+	return fmt.Sprintf("input_%s_middlewares", name)
+}
+
+func getConfigurableOutputMiddlewaresKey(name string) string {
+	return fmt.Sprintf("output_%s_middlewares", name)
+}
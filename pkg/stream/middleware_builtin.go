@@ -0,0 +1,228 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/mon"
+	"time"
+)
+
+const (
+	metricNameMiddlewareConsumeDuration   = "MiddlewareConsumeDuration"
+	metricNameMiddlewareConsumeErrorCount = "MiddlewareConsumeErrorCount"
+	metricNameMiddlewareProcessDuration   = "MiddlewareProcessDuration"
+	metricNameMiddlewareProcessErrorCount = "MiddlewareProcessErrorCount"
+	metricNameMiddlewareWriteDuration     = "MiddlewareWriteDuration"
+	metricNameMiddlewareWriteCount        = "MiddlewareWriteCount"
+	metricNameMiddlewareWriteErrorCount   = "MiddlewareWriteErrorCount"
+)
+
+// NewConsumerLoggingMiddleware logs the duration and the result of every consume operation on debug level
+func NewConsumerLoggingMiddleware(logger mon.Logger) ConsumerMiddleware {
+	return func(next ConsumeHandler) ConsumeHandler {
+		return func(ctx context.Context, msg *Message) (bool, error) {
+			start := time.Now()
+			ack, err := next(ctx, msg)
+
+			logger.WithContext(ctx).WithFields(mon.Fields{
+				"ack":      ack,
+				"duration": time.Since(start).String(),
+				"error":    err != nil,
+			}).Debug("consumed message")
+
+			return ack, err
+		}
+	}
+}
+
+// NewConsumerMetricMiddleware writes the duration and the failures of every consume operation with the input as dimension
+func NewConsumerMetricMiddleware(mw mon.MetricWriter, input string) ConsumerMiddleware {
+	return func(next ConsumeHandler) ConsumeHandler {
+		return func(ctx context.Context, msg *Message) (bool, error) {
+			start := time.Now()
+			ack, err := next(ctx, msg)
+
+			writeMiddlewareMetrics(mw, "Input", input, metricNameMiddlewareConsumeDuration, metricNameMiddlewareConsumeErrorCount, start, err)
+
+			return ack, err
+		}
+	}
+}
+
+// NewConsumerRecoveryMiddleware turns a panic of the callback into an error, so the consumer keeps running
+func NewConsumerRecoveryMiddleware() ConsumerMiddleware {
+	return func(next ConsumeHandler) ConsumeHandler {
+		return func(ctx context.Context, msg *Message) (ack bool, err error) {
+			defer func() {
+				if rp := recover(); rp != nil {
+					ack, err = false, recoveredError(rp)
+				}
+			}()
+
+			return next(ctx, msg)
+		}
+	}
+}
+
+func NewPipelineLoggingMiddleware(logger mon.Logger) PipelineMiddleware {
+	return func(next ProcessHandler) ProcessHandler {
+		return func(ctx context.Context, messages []*Message) ([]*Message, error) {
+			start := time.Now()
+			processed, err := next(ctx, messages)
+
+			logger.WithContext(ctx).WithFields(mon.Fields{
+				"received":  len(messages),
+				"processed": len(processed),
+				"duration":  time.Since(start).String(),
+				"error":     err != nil,
+			}).Debug("processed messages")
+
+			return processed, err
+		}
+	}
+}
+
+func NewPipelineMetricMiddleware(mw mon.MetricWriter, input string) PipelineMiddleware {
+	return func(next ProcessHandler) ProcessHandler {
+		return func(ctx context.Context, messages []*Message) ([]*Message, error) {
+			start := time.Now()
+			processed, err := next(ctx, messages)
+
+			writeMiddlewareMetrics(mw, "Input", input, metricNameMiddlewareProcessDuration, metricNameMiddlewareProcessErrorCount, start, err)
+
+			return processed, err
+		}
+	}
+}
+
+func NewPipelineRecoveryMiddleware() PipelineMiddleware {
+	return func(next ProcessHandler) ProcessHandler {
+		return func(ctx context.Context, messages []*Message) (processed []*Message, err error) {
+			defer func() {
+				if rp := recover(); rp != nil {
+					processed, err = nil, recoveredError(rp)
+				}
+			}()
+
+			return next(ctx, messages)
+		}
+	}
+}
+
+func NewOutputLoggingMiddleware(logger mon.Logger) OutputMiddleware {
+	return func(next WriteHandler) WriteHandler {
+		return func(ctx context.Context, batch []*Message) error {
+			start := time.Now()
+			err := next(ctx, batch)
+
+			logger.WithContext(ctx).WithFields(mon.Fields{
+				"count":    len(batch),
+				"duration": time.Since(start).String(),
+				"error":    err != nil,
+			}).Debug("wrote messages")
+
+			return err
+		}
+	}
+}
+
+func NewOutputMetricMiddleware(mw mon.MetricWriter, output string) OutputMiddleware {
+	return func(next WriteHandler) WriteHandler {
+		return func(ctx context.Context, batch []*Message) error {
+			start := time.Now()
+			err := next(ctx, batch)
+
+			mw.WriteOne(&mon.MetricDatum{
+				MetricName: metricNameMiddlewareWriteCount,
+				Dimensions: map[string]string{
+					"Output": output,
+				},
+				Unit:  mon.UnitCount,
+				Value: float64(len(batch)),
+			})
+
+			writeMiddlewareMetrics(mw, "Output", output, metricNameMiddlewareWriteDuration, metricNameMiddlewareWriteErrorCount, start, err)
+
+			return err
+		}
+	}
+}
+
+func NewOutputRecoveryMiddleware() OutputMiddleware {
+	return func(next WriteHandler) WriteHandler {
+		return func(ctx context.Context, batch []*Message) (err error) {
+			defer func() {
+				if rp := recover(); rp != nil {
+					err = recoveredError(rp)
+				}
+			}()
+
+			return next(ctx, batch)
+		}
+	}
+}
+
+func recoveredError(rp interface{}) error {
+	return fmt.Errorf("recovered from panic: %v", rp)
+}
+
+func writeMiddlewareMetrics(mw mon.MetricWriter, dimension string, name string, durationMetric string, errorMetric string, start time.Time, err error) {
+	dimensions := map[string]string{
+		dimension: name,
+	}
+
+	mw.WriteOne(&mon.MetricDatum{
+		MetricName: durationMetric,
+		Dimensions: dimensions,
+		Unit:       mon.UnitMilliseconds,
+		Value:      float64(time.Since(start).Nanoseconds()) / float64(time.Millisecond),
+	})
+
+	if err == nil {
+		return
+	}
+
+	mw.WriteOne(&mon.MetricDatum{
+		MetricName: errorMetric,
+		Dimensions: dimensions,
+		Unit:       mon.UnitCount,
+		Value:      1.0,
+	})
+}
+
+func newConsumerLoggingMiddlewareFromConfig(_ cfg.Config, logger mon.Logger, _ string) ConsumerMiddleware {
+	return NewConsumerLoggingMiddleware(logger)
+}
+
+func newConsumerMetricMiddlewareFromConfig(_ cfg.Config, _ mon.Logger, input string) ConsumerMiddleware {
+	return NewConsumerMetricMiddleware(mon.NewMetricDaemonWriter(), input)
+}
+
+func newConsumerRecoveryMiddlewareFromConfig(_ cfg.Config, _ mon.Logger, _ string) ConsumerMiddleware {
+	return NewConsumerRecoveryMiddleware()
+}
+
+func newPipelineLoggingMiddlewareFromConfig(_ cfg.Config, logger mon.Logger, _ string) PipelineMiddleware {
+	return NewPipelineLoggingMiddleware(logger)
+}
+
+func newPipelineMetricMiddlewareFromConfig(_ cfg.Config, _ mon.Logger, input string) PipelineMiddleware {
+	return NewPipelineMetricMiddleware(mon.NewMetricDaemonWriter(), input)
+}
+
+func newPipelineRecoveryMiddlewareFromConfig(_ cfg.Config, _ mon.Logger, _ string) PipelineMiddleware {
+	return NewPipelineRecoveryMiddleware()
+}
+
+func newOutputLoggingMiddlewareFromConfig(_ cfg.Config, logger mon.Logger, _ string) OutputMiddleware {
+	return NewOutputLoggingMiddleware(logger)
+}
+
+func newOutputMetricMiddlewareFromConfig(_ cfg.Config, _ mon.Logger, output string) OutputMiddleware {
+	return NewOutputMetricMiddleware(mon.NewMetricDaemonWriter(), output)
+}
+
+func newOutputRecoveryMiddlewareFromConfig(_ cfg.Config, _ mon.Logger, _ string) OutputMiddleware {
+	return NewOutputRecoveryMiddleware()
+}
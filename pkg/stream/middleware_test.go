@@ -0,0 +1,96 @@
+package stream_test
+
+import (
+	"context"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	streamMocks "github.com/applike/gosoline/pkg/stream/mocks"
+	"github.com/applike/gosoline/pkg/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func recordingOutputMiddleware(name string, calls *[]string) stream.OutputMiddleware {
+	return func(next stream.WriteHandler) stream.WriteHandler {
+		return func(ctx context.Context, batch []*stream.Message) error {
+			*calls = append(*calls, name)
+
+			for _, msg := range batch {
+				msg.Attributes[name] = true
+			}
+
+			return next(ctx, batch)
+		}
+	}
+}
+
+func TestNewOutputWithMiddlewares(t *testing.T) {
+	calls := make([]string, 0)
+	memory := stream.NewOutputMemory()
+
+	output := stream.NewOutputWithMiddlewares(
+		memory,
+		recordingOutputMiddleware("first", &calls),
+		recordingOutputMiddleware("second", &calls),
+	)
+
+	err := output.WriteOne(context.Background(), &stream.Message{
+		Attributes: map[string]interface{}{},
+		Body:       "foobar",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, calls, "the middlewares should be called in order")
+	assert.Equal(t, 1, memory.Size())
+	assert.Contains(t, memory.Messages()[0].Attributes, "first")
+	assert.Contains(t, memory.Messages()[0].Attributes, "second")
+}
+
+func TestNewOutputRecoveryMiddleware(t *testing.T) {
+	output := new(streamMocks.Output)
+	output.On("Write", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		panic("write failed")
+	})
+
+	wrapped := stream.NewOutputWithMiddlewares(output, stream.NewOutputRecoveryMiddleware())
+	err := wrapped.WriteOne(context.Background(), &stream.Message{})
+
+	assert.EqualError(t, err, "recovered from panic: write failed")
+}
+
+func TestConsumer_RunRecoveryMiddleware(t *testing.T) {
+	msg := &stream.Message{
+		Body: "foobar",
+	}
+
+	callback := new(streamMocks.ConsumerCallback)
+	callback.On("Consume", mock.Anything, msg).Run(func(args mock.Arguments) {
+		panic("consume failed")
+	}).Once()
+
+	logger := monMocks.NewLoggerMockedAll()
+	metric := monMocks.NewMetricWriterMockedAll()
+	input := stream.NewInMemoryInput(&stream.InMemorySettings{})
+	deadLetter := stream.NewOutputMemory()
+
+	consumer := stream.NewConsumer(callback)
+	consumer.Use(stream.NewConsumerRecoveryMiddleware())
+
+	err := consumer.BootWithInterfaces(logger, tracing.NewNoopTracer(), metric, input, deadLetter, &stream.ConsumerSettings{
+		IdleTimeout: time.Hour,
+	})
+	assert.NoError(t, err)
+
+	input.Publish(msg)
+	go input.Stop()
+
+	err = consumer.Run(context.Background())
+	assert.NoError(t, err, "the consumer should keep running after a panic of the callback")
+
+	callback.AssertExpectations(t)
+	assert.Equal(t, 1, deadLetter.Size(), "the recovered panic should be handled as error")
+	assert.Equal(t, "recovered from panic: consume failed", deadLetter.Messages()[0].Attributes[stream.AttributeDeadLetterError])
+	assert.True(t, input.IsAcked(msg))
+}
@@ -4,6 +4,7 @@ package mocks
 
 import mock "github.com/stretchr/testify/mock"
 import stream "github.com/applike/gosoline/pkg/stream"
+import time "time"
 
 // AcknowledgeableInput is an autogenerated mock type for the AcknowledgeableInput type
 type AcknowledgeableInput struct {
@@ -23,3 +24,17 @@ func (_m *AcknowledgeableInput) Ack(msg *stream.Message) error {
 
 	return r0
 }
+
+// Nack provides a mock function with given fields: msg, delay
+func (_m *AcknowledgeableInput) Nack(msg *stream.Message, delay time.Duration) error {
+	ret := _m.Called(msg, delay)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*stream.Message, time.Duration) error); ok {
+		r0 = rf(msg, delay)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -9,3 +9,9 @@ type Output interface {
 	WriteOne(ctx context.Context, msg *Message) error
 	Write(ctx context.Context, batch []*Message) error
 }
+
+// ClosableOutput has to be closed on shutdown to write out everything it still holds
+type ClosableOutput interface {
+	Output
+	Close()
+}
@@ -0,0 +1,375 @@
+package stream
+
+import (
+	"context"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/kernel"
+	"github.com/applike/gosoline/pkg/mon"
+	"sync"
+	"time"
+)
+
+const (
+	OutputTypeBuffered = "buffered"
+
+	metricNameBufferedOutputWriteCount = "BufferedOutputWriteCount"
+	metricNameBufferedOutputErrorCount = "BufferedOutputErrorCount"
+)
+
+type BufferedOutputSettings struct {
+	// Name is used as dimension of the metrics
+	Name      string
+	BatchSize int
+	MaxBytes  int
+	Interval  time.Duration
+}
+
+// BufferedOutputErrorHandler is called with all messages of a batch the underlying output failed to write
+type BufferedOutputErrorHandler func(ctx context.Context, batch []*Message, err error)
+
+// BufferedOutput collects the written messages and passes them in batches to the underlying output.
+// A batch is written as soon as it reaches the batch size or the max bytes or when the interval passed.
+// After the output was closed, all messages are written directly.
+type BufferedOutput struct {
+	logger   mon.Logger
+	mw       mon.MetricWriter
+	output   Output
+	settings BufferedOutputSettings
+
+	lck          sync.Mutex
+	batch        []*Message
+	bytes        int
+	closed       bool
+	errorHandler BufferedOutputErrorHandler
+
+	flush     chan []*Message
+	writers   sync.WaitGroup
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewBufferedOutput(logger mon.Logger, output Output, settings BufferedOutputSettings) *BufferedOutput {
+	mw := mon.NewMetricDaemonWriter(getBufferedOutputDefaultMetrics(settings.Name)...)
+
+	return NewBufferedOutputWithInterfaces(logger, mw, output, settings)
+}
+
+func NewBufferedOutputWithInterfaces(logger mon.Logger, mw mon.MetricWriter, output Output, settings BufferedOutputSettings) *BufferedOutput {
+	if settings.BatchSize <= 0 {
+		settings.BatchSize = 10
+	}
+
+	if settings.Interval <= 0 {
+		settings.Interval = time.Second
+	}
+
+	o := &BufferedOutput{
+		logger:   logger,
+		mw:       mw,
+		output:   output,
+		settings: settings,
+		batch:    make([]*Message, 0, settings.BatchSize),
+		flush:    make(chan []*Message, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	o.errorHandler = o.logError
+
+	go o.run()
+
+	return o
+}
+
+// OnError replaces the default error handler, which only logs the error
+func (o *BufferedOutput) OnError(handler BufferedOutputErrorHandler) {
+	o.lck.Lock()
+	defer o.lck.Unlock()
+
+	o.errorHandler = handler
+}
+
+func (o *BufferedOutput) WriteOne(ctx context.Context, msg *Message) error {
+	return o.Write(ctx, []*Message{msg})
+}
+
+// Write adds the messages to the current batch. Write errors are reported to the error handler instead of the caller.
+func (o *BufferedOutput) Write(ctx context.Context, batch []*Message) error {
+	o.lck.Lock()
+
+	if o.closed {
+		o.lck.Unlock()
+		return o.output.Write(ctx, batch)
+	}
+
+	for i, msg := range batch {
+		o.batch = append(o.batch, msg)
+		o.bytes += len(msg.Body)
+
+		if !o.isFull() {
+			continue
+		}
+
+		full := o.swapBatch()
+
+		// the output isn't closed before all writers handed over their full batches
+		o.writers.Add(1)
+		o.lck.Unlock()
+		o.flush <- full
+		o.writers.Done()
+		o.lck.Lock()
+
+		if o.closed {
+			o.lck.Unlock()
+			return o.writeRemaining(ctx, batch[i+1:])
+		}
+	}
+
+	o.lck.Unlock()
+
+	return nil
+}
+
+func (o *BufferedOutput) writeRemaining(ctx context.Context, batch []*Message) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	return o.output.Write(ctx, batch)
+}
+
+// Close writes the remaining messages and waits for all pending batches to be written
+func (o *BufferedOutput) Close() {
+	o.closeOnce.Do(func() {
+		close(o.stop)
+		<-o.done
+	})
+}
+
+func (o *BufferedOutput) isFull() bool {
+	if len(o.batch) >= o.settings.BatchSize {
+		return true
+	}
+
+	return o.settings.MaxBytes > 0 && o.bytes >= o.settings.MaxBytes
+}
+
+func (o *BufferedOutput) swapBatch() []*Message {
+	batch := o.batch
+
+	o.batch = make([]*Message, 0, o.settings.BatchSize)
+	o.bytes = 0
+
+	return batch
+}
+
+func (o *BufferedOutput) run() {
+	defer close(o.done)
+
+	ticker := time.NewTicker(o.settings.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case batch := <-o.flush:
+			o.writeBatch(batch)
+
+		case <-ticker.C:
+			o.lck.Lock()
+			batch := o.swapBatch()
+			o.lck.Unlock()
+
+			o.writeBatch(batch)
+
+		case <-o.stop:
+			o.lck.Lock()
+			o.closed = true
+			batch := o.swapBatch()
+			o.lck.Unlock()
+
+			o.drain()
+			o.writeBatch(batch)
+
+			return
+		}
+	}
+}
+
+// drain writes the full batches until no writer is left which is still handing over a batch
+func (o *BufferedOutput) drain() {
+	writersDone := make(chan struct{})
+
+	go func() {
+		o.writers.Wait()
+		close(writersDone)
+	}()
+
+	for {
+		select {
+		case pending := <-o.flush:
+			o.writeBatch(pending)
+
+		case <-writersDone:
+			for {
+				select {
+				case pending := <-o.flush:
+					o.writeBatch(pending)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (o *BufferedOutput) writeBatch(batch []*Message) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx := context.Background()
+	err := o.output.Write(ctx, batch)
+
+	metricName := metricNameBufferedOutputWriteCount
+	if err != nil {
+		metricName = metricNameBufferedOutputErrorCount
+	}
+
+	o.mw.WriteOne(&mon.MetricDatum{
+		MetricName: metricName,
+		Dimensions: map[string]string{
+			"Output": o.settings.Name,
+		},
+		Unit:  mon.UnitCount,
+		Value: float64(len(batch)),
+	})
+
+	if err == nil {
+		return
+	}
+
+	o.lck.Lock()
+	handler := o.errorHandler
+	o.lck.Unlock()
+
+	handler(ctx, batch, err)
+}
+
+func (o *BufferedOutput) logError(_ context.Context, batch []*Message, err error) {
+	o.logger.Errorf(err, "could not write a batch of %d buffered messages", len(batch))
+}
+
+func getBufferedOutputDefaultMetrics(name string) mon.MetricData {
+	return mon.MetricData{
+		{
+			Priority:   mon.PriorityHigh,
+			MetricName: metricNameBufferedOutputWriteCount,
+			Dimensions: map[string]string{
+				"Output": name,
+			},
+			Unit:  mon.UnitCount,
+			Value: 0.0,
+		},
+		{
+			Priority:   mon.PriorityHigh,
+			MetricName: metricNameBufferedOutputErrorCount,
+			Dimensions: map[string]string{
+				"Output": name,
+			},
+			Unit:  mon.UnitCount,
+			Value: 0.0,
+		},
+	}
+}
+
+// BufferedOutputFlusher closes all buffered and file outputs created from the config on the shutdown of the kernel,
+// so no buffered message gets lost. Applications using these outputs add it to their kernel with
+// NewBufferedOutputFlusherModuleFactory.
+type BufferedOutputFlusher struct {
+	kernel.BackgroundModule
+
+	lck     sync.Mutex
+	outputs []ClosableOutput
+}
+
+var bufferedOutputFlusherContainer = struct {
+	sync.Mutex
+	instance *BufferedOutputFlusher
+}{}
+
+func NewBufferedOutputFlusherModuleFactory() kernel.ModuleFactory {
+	return func(_ cfg.Config, _ mon.Logger) (map[string]kernel.Module, error) {
+		return map[string]kernel.Module{
+			"stream_buffered_output_flusher": ProvideBufferedOutputFlusher(),
+		}, nil
+	}
+}
+
+func ProvideBufferedOutputFlusher() *BufferedOutputFlusher {
+	bufferedOutputFlusherContainer.Lock()
+	defer bufferedOutputFlusherContainer.Unlock()
+
+	if bufferedOutputFlusherContainer.instance != nil {
+		return bufferedOutputFlusherContainer.instance
+	}
+
+	bufferedOutputFlusherContainer.instance = &BufferedOutputFlusher{
+		outputs: make([]ClosableOutput, 0),
+	}
+
+	return bufferedOutputFlusherContainer.instance
+}
+
+func (f *BufferedOutputFlusher) Register(output ClosableOutput) {
+	f.lck.Lock()
+	defer f.lck.Unlock()
+
+	f.outputs = append(f.outputs, output)
+}
+
+func (f *BufferedOutputFlusher) Boot(_ cfg.Config, _ mon.Logger) error {
+	return nil
+}
+
+func (f *BufferedOutputFlusher) Run(ctx context.Context) error {
+	<-ctx.Done()
+
+	f.lck.Lock()
+	outputs := f.outputs
+	f.lck.Unlock()
+
+	// outputs are registered after the outputs they write to, so they are closed first
+	for i := len(outputs) - 1; i >= 0; i-- {
+		outputs[i].Close()
+	}
+
+	return nil
+}
+
+type bufferedOutputConfiguration struct {
+	Output    string `cfg:"output"`
+	BatchSize int    `cfg:"batch_size"`
+	MaxBytes  int    `cfg:"max_bytes"`
+	// Interval in seconds
+	Interval int `cfg:"interval"`
+}
+
+func newBufferedOutputFromConfig(config cfg.Config, logger mon.Logger, name string) Output {
+	key := getConfigurableOutputKey(name)
+
+	configuration := bufferedOutputConfiguration{}
+	config.UnmarshalKey(key, &configuration)
+
+	output := NewConfigurableOutput(config, logger, configuration.Output)
+	buffered := NewBufferedOutput(logger, output, BufferedOutputSettings{
+		Name:      name,
+		BatchSize: configuration.BatchSize,
+		MaxBytes:  configuration.MaxBytes,
+		Interval:  time.Duration(configuration.Interval) * time.Second,
+	})
+
+	ProvideBufferedOutputFlusher().Register(buffered)
+
+	return buffered
+}
@@ -0,0 +1,145 @@
+package stream_test
+
+import (
+	"context"
+	"fmt"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	streamMocks "github.com/applike/gosoline/pkg/stream/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func waitForOutputSize(t *testing.T, output *stream.OutputMemory, size int, msgAndArgs ...interface{}) {
+	deadline := time.Now().Add(time.Second)
+
+	for output.Size() < size && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.Equal(t, size, output.Size(), msgAndArgs...)
+}
+
+func TestBufferedOutput_FlushBatchSize(t *testing.T) {
+	memory := stream.NewOutputMemory()
+	output := stream.NewBufferedOutputWithInterfaces(monMocks.NewLoggerMockedAll(), monMocks.NewMetricWriterMockedAll(), memory, stream.BufferedOutputSettings{
+		BatchSize: 2,
+		Interval:  time.Hour,
+	})
+
+	for i := 0; i < 3; i++ {
+		err := output.WriteOne(context.Background(), &stream.Message{Body: fmt.Sprintf("msg %d", i)})
+		assert.NoError(t, err)
+	}
+
+	waitForOutputSize(t, memory, 2, "the first batch should be written")
+
+	output.Close()
+
+	assert.Equal(t, 3, memory.Size(), "the remaining message should be written on close")
+
+	err := output.WriteOne(context.Background(), &stream.Message{Body: "closed"})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, memory.Size(), "messages should be written directly after close")
+}
+
+func TestBufferedOutput_CloseWithConcurrentWriters(t *testing.T) {
+	memory := stream.NewOutputMemory()
+	output := stream.NewBufferedOutputWithInterfaces(monMocks.NewLoggerMockedAll(), monMocks.NewMetricWriterMockedAll(), memory, stream.BufferedOutputSettings{
+		BatchSize: 1,
+		Interval:  time.Hour,
+	})
+
+	done := make(chan struct{})
+
+	for w := 0; w < 10; w++ {
+		go func(w int) {
+			defer func() {
+				done <- struct{}{}
+			}()
+
+			for i := 0; i < 10; i++ {
+				err := output.WriteOne(context.Background(), &stream.Message{Body: fmt.Sprintf("msg %d %d", w, i)})
+				assert.NoError(t, err)
+			}
+		}(w)
+	}
+
+	output.Close()
+
+	for w := 0; w < 10; w++ {
+		<-done
+	}
+
+	assert.Equal(t, 100, memory.Size(), "no message should get lost on close")
+}
+
+func TestBufferedOutput_FlushMaxBytes(t *testing.T) {
+	memory := stream.NewOutputMemory()
+	output := stream.NewBufferedOutputWithInterfaces(monMocks.NewLoggerMockedAll(), monMocks.NewMetricWriterMockedAll(), memory, stream.BufferedOutputSettings{
+		BatchSize: 100,
+		MaxBytes:  10,
+		Interval:  time.Hour,
+	})
+	defer output.Close()
+
+	err := output.WriteOne(context.Background(), &stream.Message{Body: "0123456789"})
+	assert.NoError(t, err)
+
+	waitForOutputSize(t, memory, 1)
+}
+
+func TestBufferedOutput_FlushInterval(t *testing.T) {
+	memory := stream.NewOutputMemory()
+	output := stream.NewBufferedOutputWithInterfaces(monMocks.NewLoggerMockedAll(), monMocks.NewMetricWriterMockedAll(), memory, stream.BufferedOutputSettings{
+		BatchSize: 100,
+		Interval:  10 * time.Millisecond,
+	})
+	defer output.Close()
+
+	err := output.WriteOne(context.Background(), &stream.Message{Body: "foobar"})
+	assert.NoError(t, err)
+
+	waitForOutputSize(t, memory, 1)
+}
+
+func TestBufferedOutput_OnError(t *testing.T) {
+	msg := &stream.Message{Body: "foobar"}
+
+	inner := new(streamMocks.Output)
+	inner.On("Write", mock.Anything, []*stream.Message{msg}).Return(fmt.Errorf("write failed")).Once()
+
+	metric := monMocks.NewMetricWriterMockedAll()
+	output := stream.NewBufferedOutputWithInterfaces(monMocks.NewLoggerMockedAll(), metric, inner, stream.BufferedOutputSettings{
+		BatchSize: 100,
+		Interval:  time.Hour,
+	})
+
+	var failed []*stream.Message
+	output.OnError(func(ctx context.Context, batch []*stream.Message, err error) {
+		assert.EqualError(t, err, "write failed")
+		failed = batch
+	})
+
+	err := output.WriteOne(context.Background(), msg)
+	assert.NoError(t, err, "write errors should be reported to the error handler")
+
+	output.Close()
+
+	assert.Equal(t, []*stream.Message{msg}, failed)
+	inner.AssertExpectations(t)
+	metric.AssertNumberOfCalls(t, "WriteOne", 1)
+}
+
+func TestBufferedOutputFlusherModuleFactory(t *testing.T) {
+	logger := monMocks.NewLoggerMockedAll()
+	factory := stream.NewBufferedOutputFlusherModuleFactory()
+
+	modules, err := factory(nil, logger)
+
+	assert.NoError(t, err)
+	assert.Len(t, modules, 1)
+	assert.Same(t, stream.ProvideBufferedOutputFlusher(), modules["stream_buffered_output_flusher"], "the factory should add the shared flusher")
+}
@@ -5,36 +5,67 @@ import (
 	"github.com/applike/gosoline/pkg/cfg"
 	"github.com/applike/gosoline/pkg/mon"
 	"github.com/applike/gosoline/pkg/sqs"
+	"sync"
 )
 
 const (
-	OutputTypeFile    = "file"
-	OutputTypeKinesis = "kinesis"
-	OutputTypeRedis   = "redis"
-	OutputTypeSns     = "sns"
-	OutputTypeSqs     = "sqs"
+	OutputTypeFile        = "file"
+	OutputTypeInMemory    = "inMemory"
+	OutputTypeKinesis     = "kinesis"
+	OutputTypeRedis       = "redis"
+	OutputTypeRedisStream = "redisStream"
+	OutputTypeSns         = "sns"
+	OutputTypeSqs         = "sqs"
 )
 
+type OutputFactory func(config cfg.Config, logger mon.Logger, name string) Output
+
+var outputFactories = struct {
+	sync.Mutex
+	factories map[string]OutputFactory
+}{
+	factories: make(map[string]OutputFactory),
+}
+
+func init() {
+	RegisterOutputFactory(OutputTypeBuffered, newBufferedOutputFromConfig)
+	RegisterOutputFactory(OutputTypeFile, newFileOutputFromConfig)
+	RegisterOutputFactory(OutputTypeInMemory, newInMemoryOutputFromConfig)
+	RegisterOutputFactory(OutputTypeKinesis, newKinesisOutputFromConfig)
+	RegisterOutputFactory(OutputTypeMulti, newMultiOutputFromConfig)
+	RegisterOutputFactory(OutputTypeRedis, newRedisListOutputFromConfig)
+	RegisterOutputFactory(OutputTypeRedisStream, newRedisStreamOutputFromConfig)
+	RegisterOutputFactory(OutputTypeRouter, newRouterOutputFromConfig)
+	RegisterOutputFactory(OutputTypeSns, newSnsOutputFromConfig)
+	RegisterOutputFactory(OutputTypeSqs, newSqsOutputFromConfig)
+}
+
+// RegisterOutputFactory makes an output type available for config driven outputs via output_<name>_type.
+// Registering a factory for an already known type replaces the existing one.
+func RegisterOutputFactory(typ string, factory OutputFactory) {
+	outputFactories.Lock()
+	defer outputFactories.Unlock()
+
+	outputFactories.factories[typ] = factory
+}
+
 func NewConfigurableOutput(config cfg.Config, logger mon.Logger, name string) Output {
 	key := fmt.Sprintf("output_%s_type", name)
 	t := config.GetString(key)
 
-	switch t {
-	case OutputTypeFile:
-		return newFileOutputFromConfig(config, logger, name)
-	case OutputTypeKinesis:
-		return newKinesisOutputFromConfig(config, logger, name)
-	case OutputTypeRedis:
-		return newRedisListOutputFromConfig(config, logger, name)
-	case OutputTypeSns:
-		return newSnsOutputFromConfig(config, logger, name)
-	case OutputTypeSqs:
-		return newSqsOutputFromConfig(config, logger, name)
-	default:
-		logger.Fatalf(fmt.Errorf("invalid input %s of type %s", name, t), "invalid input %s of type %s", name, t)
+	outputFactories.Lock()
+	factory, ok := outputFactories.factories[t]
+	outputFactories.Unlock()
+
+	if !ok {
+		logger.Fatalf(fmt.Errorf("invalid output %s of type %s", name, t), "invalid output %s of type %s", name, t)
+		return nil
 	}
 
-	return nil
+	output := factory(config, logger, name)
+	middlewares := readOutputMiddlewares(config, logger, name)
+
+	return NewOutputWithMiddlewares(output, middlewares...)
 }
 
 func newFileOutputFromConfig(config cfg.Config, logger mon.Logger, name string) Output {
@@ -42,11 +73,39 @@ func newFileOutputFromConfig(config cfg.Config, logger mon.Logger, name string)
 	settings := &FileOutputSettings{}
 	config.UnmarshalKey(key, settings)
 
-	return NewFileOutput(config, logger, settings)
+	output := NewFileOutput(config, logger, settings)
+	ProvideBufferedOutputFlusher().Register(output)
+
+	return output
+}
+
+type inMemoryOutputConfiguration struct {
+	Loopback string `cfg:"loopback"`
+}
+
+// newInMemoryOutputFromConfig publishes all messages to the in memory input named by the loopback setting if it is configured
+func newInMemoryOutputFromConfig(config cfg.Config, _ mon.Logger, name string) Output {
+	key := getConfigurableOutputKey(name)
+	configuration := inMemoryOutputConfiguration{}
+
+	if config.IsSet(key) {
+		config.UnmarshalKey(key, &configuration)
+	}
+
+	output := ProvideOutputMemory(name)
+
+	if configuration.Loopback != "" {
+		output.SetLoopback(ProvideInMemoryInput(configuration.Loopback, &InMemorySettings{}))
+	}
+
+	return output
 }
 
 type kinesisOutputConfiguration struct {
-	StreamName string `cfg:"streamName"`
+	StreamName          string `cfg:"streamName"`
+	MaxAttempts         int    `cfg:"max_attempts"`
+	Aggregation         bool   `cfg:"aggregation"`
+	AggregationMaxBytes int    `cfg:"aggregation_max_bytes"`
 }
 
 func newKinesisOutputFromConfig(config cfg.Config, logger mon.Logger, name string) Output {
@@ -55,7 +114,10 @@ func newKinesisOutputFromConfig(config cfg.Config, logger mon.Logger, name strin
 	config.UnmarshalKey(key, settings)
 
 	return NewKinesisOutput(config, logger, &KinesisOutputSettings{
-		StreamName: settings.StreamName,
+		StreamName:          settings.StreamName,
+		MaxAttempts:         settings.MaxAttempts,
+		Aggregation:         settings.Aggregation,
+		AggregationMaxBytes: settings.AggregationMaxBytes,
 	})
 }
 
@@ -86,11 +148,41 @@ func newRedisListOutputFromConfig(config cfg.Config, logger mon.Logger, name str
 	})
 }
 
+type redisStreamOutputConfiguration struct {
+	Project      string `cfg:"project"`
+	Family       string `cfg:"family"`
+	Application  string `cfg:"application"`
+	ServerName   string `cfg:"serverName"`
+	Key          string `cfg:"key"`
+	MaxLen       int64  `cfg:"maxLen"`
+	MaxLenApprox bool   `cfg:"maxLenApprox"`
+}
+
+func newRedisStreamOutputFromConfig(config cfg.Config, logger mon.Logger, name string) Output {
+	key := getConfigurableOutputKey(name)
+
+	configuration := redisStreamOutputConfiguration{}
+	config.UnmarshalKey(key, &configuration)
+
+	return NewRedisStreamOutput(config, logger, &RedisStreamOutputSettings{
+		AppId: cfg.AppId{
+			Project:     configuration.Project,
+			Family:      configuration.Family,
+			Application: configuration.Application,
+		},
+		ServerName:   configuration.ServerName,
+		Key:          configuration.Key,
+		MaxLen:       configuration.MaxLen,
+		MaxLenApprox: configuration.MaxLenApprox,
+	})
+}
+
 type snsOutputConfiguration struct {
-	Project     string `cfg:"project"`
-	Family      string `cfg:"family"`
-	Application string `cfg:"application"`
-	TopicId     string `cfg:"topic_id"`
+	Project     string          `cfg:"project"`
+	Family      string          `cfg:"family"`
+	Application string          `cfg:"application"`
+	TopicId     string          `cfg:"topic_id"`
+	Offload     OffloadSettings `cfg:"offload"`
 }
 
 func newSnsOutputFromConfig(config cfg.Config, logger mon.Logger, name string) Output {
@@ -106,6 +198,7 @@ func newSnsOutputFromConfig(config cfg.Config, logger mon.Logger, name string) O
 			Application: configuration.Application,
 		},
 		TopicId: configuration.TopicId,
+		Offload: configuration.Offload,
 	})
 }
 
@@ -116,6 +209,7 @@ type sqsOutputConfiguration struct {
 	QueueId           string            `cfg:"queue_id"`
 	VisibilityTimeout int               `cfg:"visibility_timeout"`
 	RedrivePolicy     sqs.RedrivePolicy `cfg:"redrive_policy"`
+	Offload           OffloadSettings   `cfg:"offload"`
 }
 
 func newSqsOutputFromConfig(config cfg.Config, logger mon.Logger, name string) Output {
@@ -133,6 +227,7 @@ func newSqsOutputFromConfig(config cfg.Config, logger mon.Logger, name string) O
 		QueueId:           configuration.QueueId,
 		VisibilityTimeout: configuration.VisibilityTimeout,
 		RedrivePolicy:     configuration.RedrivePolicy,
+		Offload:           configuration.Offload,
 	})
 }
 
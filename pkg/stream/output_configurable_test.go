@@ -0,0 +1,28 @@
+package stream_test
+
+import (
+	"github.com/applike/gosoline/pkg/cfg"
+	configMocks "github.com/applike/gosoline/pkg/cfg/mocks"
+	"github.com/applike/gosoline/pkg/mon"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	streamMocks "github.com/applike/gosoline/pkg/stream/mocks"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewConfigurableOutput_CustomType(t *testing.T) {
+	config := new(configMocks.Config)
+	config.On("GetString", "output_custom_type").Return("inHouse")
+	config.On("IsSet", "output_custom_middlewares").Return(false)
+
+	logger := monMocks.NewLoggerMockedAll()
+	output := new(streamMocks.Output)
+
+	stream.RegisterOutputFactory("inHouse", func(_ cfg.Config, _ mon.Logger, name string) stream.Output {
+		assert.Equal(t, "custom", name)
+		return output
+	})
+
+	assert.Equal(t, output, stream.NewConfigurableOutput(config, logger, "custom"))
+}
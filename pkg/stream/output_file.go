@@ -1,24 +1,44 @@
 package stream
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/applike/gosoline/pkg/cfg"
 	"github.com/applike/gosoline/pkg/mon"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 )
 
 type FileOutputSettings struct {
 	Filename string `cfg:"filename"`
 	Append   bool   `cfg:"append"`
+	// MaxBytes and RotateInterval start a new file once the current one reached the size or age. The names of
+	// rotated files get the time of their creation appended, so they sort in the order they were written.
+	MaxBytes int64 `cfg:"max_bytes"`
+	// RotateInterval in seconds
+	RotateInterval int `cfg:"rotate_interval"`
+	// Compression gzip writes every batch as separate gzip member, so the file stays readable after every write
+	Compression string `cfg:"compression"`
 }
 
 type fileOutput struct {
 	logger   mon.Logger
 	settings *FileOutputSettings
+
+	lck     sync.Mutex
+	file    *os.File
+	opened  bool
+	size    int64
+	created time.Time
 }
 
-func NewFileOutput(_ cfg.Config, logger mon.Logger, settings *FileOutputSettings) Output {
+func NewFileOutput(_ cfg.Config, logger mon.Logger, settings *FileOutputSettings) ClosableOutput {
 	return &fileOutput{
 		logger:   logger,
 		settings: settings,
@@ -30,40 +50,149 @@ func (o *fileOutput) WriteOne(ctx context.Context, msg *Message) error {
 }
 
 func (o *fileOutput) Write(ctx context.Context, batch []*Message) error {
-	flags := os.O_CREATE | os.O_WRONLY
-	if o.settings.Append {
-		flags = flags | os.O_APPEND
-	}
+	o.lck.Lock()
+	defer o.lck.Unlock()
 
-	file, err := os.OpenFile(o.settings.Filename, flags, 0644)
+	data, err := o.encodeBatch(batch)
 
 	if err != nil {
 		return err
 	}
 
+	if err = o.rotate(); err != nil {
+		return err
+	}
+
+	n, err := o.file.Write(data)
+	o.size += int64(n)
+
+	return err
+}
+
+// Close closes the current file, it is opened again by the next write
+func (o *fileOutput) Close() {
+	o.lck.Lock()
+	defer o.lck.Unlock()
+
+	o.closeFile()
+}
+
+func (o *fileOutput) closeFile() {
+	if o.file == nil {
+		return
+	}
+
+	if err := o.file.Close(); err != nil {
+		o.logger.Errorf(err, "can not close the file %s", o.file.Name())
+	}
+
+	o.file = nil
+}
+
+func (o *fileOutput) encodeBatch(batch []*Message) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
 	for _, msg := range batch {
 		data, err := json.Marshal(*msg)
 
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		_, err = file.Write(data)
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
 
-		if err != nil {
-			return err
-		}
+	if o.settings.Compression != CompressionGzip {
+		return buf.Bytes(), nil
+	}
 
-		_, err = file.Write([]byte{'\n'})
+	compressed := &bytes.Buffer{}
+	writer := gzip.NewWriter(compressed)
 
-		if err != nil {
-			return err
-		}
+	if _, err := writer.Write(buf.Bytes()); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return compressed.Bytes(), nil
+}
+
+func (o *fileOutput) isRotating() bool {
+	return o.settings.MaxBytes > 0 || o.settings.RotateInterval > 0
+}
+
+func (o *fileOutput) getRotateInterval() time.Duration {
+	return time.Duration(o.settings.RotateInterval) * time.Second
+}
+
+func (o *fileOutput) needsRotation() bool {
+	if o.file == nil {
+		return true
+	}
+
+	if o.settings.MaxBytes > 0 && o.size >= o.settings.MaxBytes {
+		return true
+	}
+
+	return o.settings.RotateInterval > 0 && time.Since(o.created) >= o.getRotateInterval()
+}
+
+func (o *fileOutput) rotate() error {
+	if !o.needsRotation() {
+		return nil
+	}
+
+	o.closeFile()
+
+	// a file which was closed by a shutdown is continued instead of being truncated
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if (o.settings.Append || o.opened) && !o.isRotating() {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	now := time.Now()
+	filename := o.settings.Filename
+
+	if o.isRotating() {
+		filename = o.rotatedFilename(now)
+	}
+
+	file, err := os.OpenFile(filename, flags, 0644)
+
+	if err != nil {
+		return err
 	}
 
-	if err = file.Close(); err != nil {
+	stat, err := file.Stat()
+
+	if err != nil {
+		_ = file.Close()
 		return err
 	}
 
+	o.file = file
+	o.opened = true
+	o.size = stat.Size()
+	o.created = now
+
 	return nil
 }
+
+// rotatedFilename inserts the time before the extension, e.g. events.json becomes events-20191231T235959000000000.json
+func (o *fileOutput) rotatedFilename(now time.Time) string {
+	ext := filepath.Ext(o.settings.Filename)
+	base := strings.TrimSuffix(o.settings.Filename, ext)
+	timestamp := fmt.Sprintf("%s%09d", now.Format("20060102T150405"), now.Nanosecond())
+
+	filename := fmt.Sprintf("%s-%s%s", base, timestamp, ext)
+
+	if o.settings.Compression == CompressionGzip && ext != ".gz" {
+		filename = filename + ".gz"
+	}
+
+	return filename
+}
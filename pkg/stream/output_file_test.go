@@ -0,0 +1,108 @@
+package stream_test
+
+import (
+	"context"
+	"fmt"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileOutput_WriteRotationAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-output")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	logger := monMocks.NewLoggerMockedAll()
+	output := stream.NewFileOutput(nil, logger, &stream.FileOutputSettings{
+		Filename:    filepath.Join(dir, "events.json"),
+		MaxBytes:    1,
+		Compression: stream.CompressionGzip,
+	})
+
+	for i := 0; i < 3; i++ {
+		err = output.WriteOne(context.Background(), &stream.Message{Attributes: map[string]interface{}{}, Body: fmt.Sprintf("msg %d", i)})
+		assert.NoError(t, err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "events-*.json.gz"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 3, "every write should start a new file")
+
+	input := stream.NewFileInputWithInterfaces(logger, stream.FileSettings{
+		Filename:   dir,
+		ReplayRate: 1000,
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- input.Run()
+	}()
+
+	start := time.Now()
+	bodies := make([]string, 0)
+
+	for msg := range input.Data() {
+		bodies = append(bodies, msg.Body)
+	}
+
+	assert.NoError(t, <-done)
+	assert.Equal(t, []string{"msg 0", "msg 1", "msg 2"}, bodies, "the files should be read in order")
+	assert.True(t, time.Since(start) >= 2*time.Millisecond, "the messages should be replayed at the configured rate")
+}
+
+func TestFileOutput_WriteSameFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-output")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "events.json")
+	logger := monMocks.NewLoggerMockedAll()
+	output := stream.NewFileOutput(nil, logger, &stream.FileOutputSettings{
+		Filename: filename,
+	})
+
+	for i := 0; i < 2; i++ {
+		err = output.WriteOne(context.Background(), &stream.Message{Body: fmt.Sprintf("msg %d", i)})
+		assert.NoError(t, err)
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "\n"), "the batches should be written to the same file")
+	assert.Contains(t, string(data), "msg 0")
+	assert.Contains(t, string(data), "msg 1")
+}
+
+func TestFileOutput_WriteAfterClose(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-output")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "events.json")
+	logger := monMocks.NewLoggerMockedAll()
+	output := stream.NewFileOutput(nil, logger, &stream.FileOutputSettings{
+		Filename: filename,
+	})
+
+	err = output.WriteOne(context.Background(), &stream.Message{Body: "msg 0"})
+	assert.NoError(t, err)
+
+	output.Close()
+
+	err = output.WriteOne(context.Background(), &stream.Message{Body: "msg 1"})
+	assert.NoError(t, err)
+
+	output.Close()
+
+	data, err := ioutil.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "msg 0", "the file should not be truncated after it was closed")
+	assert.Contains(t, string(data), "msg 1")
+}
@@ -15,10 +15,23 @@ import (
 	"time"
 )
 
-const kinesisBatchSizeMax = 500
+const (
+	AttributeKinesisPartitionKey = "partitionKey"
+
+	kinesisBatchSizeMax            = 500
+	kinesisBatchBytesMax           = 5 * 1024 * 1024
+	kinesisRecordBytesMax          = 1024 * 1024
+	kinesisDefaultMaxAttempts      = 10
+	kinesisDefaultAggregationBytes = kinesisRecordBytesMax - 1024
+)
 
 type KinesisOutputSettings struct {
 	StreamName string
+	// MaxAttempts limits how often the failed records of a batch are written, defaults to 10
+	MaxAttempts int
+	// Aggregation combines the messages of a partition key into KPL compatible records of at most AggregationMaxBytes
+	Aggregation         bool
+	AggregationMaxBytes int
 }
 
 type kinesisOutput struct {
@@ -34,6 +47,14 @@ func NewKinesisOutput(config cfg.Config, logger mon.Logger, settings *KinesisOut
 }
 
 func NewKinesisOutputWithInterfaces(logger mon.Logger, client kinesisiface.KinesisAPI, settings *KinesisOutputSettings) Output {
+	if settings.MaxAttempts <= 0 {
+		settings.MaxAttempts = kinesisDefaultMaxAttempts
+	}
+
+	if settings.AggregationMaxBytes <= 0 || settings.AggregationMaxBytes > kinesisRecordBytesMax {
+		settings.AggregationMaxBytes = kinesisDefaultAggregationBytes
+	}
+
 	return &kinesisOutput{
 		client:   client,
 		settings: settings,
@@ -51,13 +72,17 @@ func (o *kinesisOutput) Write(ctx context.Context, batch []*Message) error {
 	}
 
 	errs := make([]error, 0)
-	chunks, err := BuildChunks(batch, kinesisBatchSizeMax)
+	records, err := o.buildRecords(batch)
 
 	if err != nil {
 		o.logger.Error(err, "could not batch all messages")
 	}
 
-	for _, chunk := range chunks {
+	if o.settings.Aggregation {
+		records = aggregateKinesisRecords(records, o.settings.AggregationMaxBytes)
+	}
+
+	for _, chunk := range o.buildChunks(records) {
 		err := o.writeBatch(chunk)
 
 		if err != nil {
@@ -72,13 +97,76 @@ func (o *kinesisOutput) Write(ctx context.Context, batch []*Message) error {
 	return errors.Wrap(errs[0], fmt.Sprintf("there were %v write errors to %v", len(errs), o.settings.StreamName))
 }
 
-func (o *kinesisOutput) writeBatch(batch [][]byte) error {
+// buildRecords uses the partition key attribute of the messages. Messages without it get a random partition key,
+// which is shared by all of them if they are aggregated.
+func (o *kinesisOutput) buildRecords(batch []*Message) ([]kinesisRecord, error) {
+	errs := 0
+	records := make([]kinesisRecord, 0, len(batch))
+	randomKey := uuid.NewV4().String()
+
+	for _, msg := range batch {
+		data, err := msg.MarshalToBytes()
+
+		if err != nil {
+			errs++
+			continue
+		}
+
+		partitionKey, _ := msg.Attributes[AttributeKinesisPartitionKey].(string)
+
+		switch {
+		case partitionKey != "":
+		case o.settings.Aggregation:
+			partitionKey = randomKey
+		default:
+			partitionKey = uuid.NewV4().String()
+		}
+
+		records = append(records, kinesisRecord{
+			partitionKey: partitionKey,
+			data:         data,
+		})
+	}
+
+	if errs > 0 {
+		return records, fmt.Errorf("there were %v errors on marshalling the messages", errs)
+	}
+
+	return records, nil
+}
+
+func (o *kinesisOutput) buildChunks(records []kinesisRecord) [][]kinesisRecord {
+	chunks := make([][]kinesisRecord, 0)
+	chunk := make([]kinesisRecord, 0, kinesisBatchSizeMax)
+	chunkBytes := 0
+
+	for _, record := range records {
+		recordBytes := len(record.data) + len(record.partitionKey)
+
+		if len(chunk) == kinesisBatchSizeMax || (len(chunk) > 0 && chunkBytes+recordBytes > kinesisBatchBytesMax) {
+			chunks = append(chunks, chunk)
+			chunk = make([]kinesisRecord, 0, kinesisBatchSizeMax)
+			chunkBytes = 0
+		}
+
+		chunk = append(chunk, record)
+		chunkBytes += recordBytes
+	}
+
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
+}
+
+func (o *kinesisOutput) writeBatch(batch []kinesisRecord) error {
 	records := make([]*kinesis.PutRecordsRequestEntry, 0, len(batch))
 
-	for _, data := range batch {
+	for _, record := range batch {
 		request := &kinesis.PutRecordsRequestEntry{
-			Data:         data,
-			PartitionKey: aws.String(uuid.NewV4().String()),
+			Data:         record.data,
+			PartitionKey: aws.String(record.partitionKey),
 		}
 
 		records = append(records, request)
@@ -87,19 +175,22 @@ func (o *kinesisOutput) writeBatch(batch [][]byte) error {
 	backoffConfig := backoff.NewExponentialBackOff()
 	backoffConfig.MaxElapsedTime = 15 * time.Minute
 
+	retries := uint64(o.settings.MaxAttempts - 1)
+
 	err := backoff.Retry(func() (err error) {
 		records, err = o.putRecordsAndCollectFailed(records)
 
 		return err
-	}, backoffConfig)
+	}, backoff.WithMaxRetries(backoffConfig, retries))
 
 	if err != nil {
-		o.logger.Error(err, "Error putting records")
+		o.logger.WithFields(mon.Fields{
+			"failed_records": len(records),
+		}).Error(err, "Error putting records")
 	}
 
 	return err
 }
-
 func (o *kinesisOutput) putRecordsAndCollectFailed(records []*kinesis.PutRecordsRequestEntry) ([]*kinesis.PutRecordsRequestEntry, error) {
 	input := kinesis.PutRecordsInput{
 		Records:    records,
@@ -46,3 +46,77 @@ func TestWriter_WriteEvents(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestKinesisOutput_WritePartitionKey(t *testing.T) {
+	kinesisClient := new(cloudMocks.KinesisAPI)
+	kinesisClient.On("PutRecords", mock.Anything).Run(func(args mock.Arguments) {
+		input := args.Get(0).(*kinesis.PutRecordsInput)
+
+		assert.Len(t, input.Records, 2)
+		assert.Equal(t, "user-1", *input.Records[0].PartitionKey)
+		assert.NotEmpty(t, *input.Records[1].PartitionKey, "messages without partition key should get a random one")
+	}).Return(&kinesis.PutRecordsOutput{
+		Records: []*kinesis.PutRecordsResultEntry{{}, {}},
+	}, nil).Once()
+
+	writer := stream.NewKinesisOutputWithInterfaces(monMocks.NewLoggerMockedAll(), kinesisClient, &stream.KinesisOutputSettings{
+		StreamName: "streamName",
+	})
+
+	err := writer.Write(context.Background(), []*stream.Message{
+		{Attributes: map[string]interface{}{stream.AttributeKinesisPartitionKey: "user-1"}, Body: "1"},
+		{Attributes: map[string]interface{}{}, Body: "2"},
+	})
+
+	assert.NoError(t, err)
+	kinesisClient.AssertExpectations(t)
+}
+
+func TestKinesisOutput_WriteMaxAttempts(t *testing.T) {
+	kinesisClient := new(cloudMocks.KinesisAPI)
+	kinesisClient.On("PutRecords", mock.Anything).Return(&kinesis.PutRecordsOutput{
+		Records: []*kinesis.PutRecordsResultEntry{{
+			ErrorCode: aws.String("ProvisionedThroughputExceededException"),
+		}},
+	}, nil).Times(2)
+
+	writer := stream.NewKinesisOutputWithInterfaces(monMocks.NewLoggerMockedAll(), kinesisClient, &stream.KinesisOutputSettings{
+		StreamName:  "streamName",
+		MaxAttempts: 2,
+	})
+
+	err := writer.WriteOne(context.Background(), &stream.Message{Body: "1"})
+
+	assert.Error(t, err)
+	kinesisClient.AssertExpectations(t)
+}
+
+func TestKinesisOutput_WriteAggregation(t *testing.T) {
+	var records []*kinesis.PutRecordsRequestEntry
+
+	kinesisClient := new(cloudMocks.KinesisAPI)
+	kinesisClient.On("PutRecords", mock.Anything).Run(func(args mock.Arguments) {
+		records = args.Get(0).(*kinesis.PutRecordsInput).Records
+	}).Return(&kinesis.PutRecordsOutput{
+		Records: []*kinesis.PutRecordsResultEntry{{}, {}},
+	}, nil).Once()
+
+	writer := stream.NewKinesisOutputWithInterfaces(monMocks.NewLoggerMockedAll(), kinesisClient, &stream.KinesisOutputSettings{
+		StreamName:  "streamName",
+		Aggregation: true,
+	})
+
+	batch := []*stream.Message{
+		{Attributes: map[string]interface{}{stream.AttributeKinesisPartitionKey: "a"}, Body: "1"},
+		{Attributes: map[string]interface{}{stream.AttributeKinesisPartitionKey: "b"}, Body: "2"},
+		{Attributes: map[string]interface{}{stream.AttributeKinesisPartitionKey: "a"}, Body: "3"},
+	}
+
+	err := writer.Write(context.Background(), batch)
+
+	assert.NoError(t, err)
+	assert.Len(t, records, 2, "the messages should be aggregated by partition key")
+	assert.Equal(t, "a", *records[0].PartitionKey)
+	assert.Equal(t, "b", *records[1].PartitionKey)
+	assert.Equal(t, []byte{0xF3, 0x89, 0x9A, 0xC2}, records[0].Data[:4], "the aggregated record should start with the magic number")
+}
@@ -1,9 +1,43 @@
 package stream
 
-import "context"
+import (
+	"context"
+	"sync"
+)
 
 type OutputMemory struct {
+	lck      sync.Mutex
 	messages []*Message
+	loopback *InMemoryInput
+}
+
+var outputMemories = struct {
+	sync.Mutex
+	instances map[string]*OutputMemory
+}{
+	instances: make(map[string]*OutputMemory),
+}
+
+// ProvideOutputMemory returns the memory output registered for the name and creates it on the first call
+func ProvideOutputMemory(name string) *OutputMemory {
+	outputMemories.Lock()
+	defer outputMemories.Unlock()
+
+	if output, ok := outputMemories.instances[name]; ok {
+		return output
+	}
+
+	outputMemories.instances[name] = NewOutputMemory()
+
+	return outputMemories.instances[name]
+}
+
+// ResetOutputMemories removes all named memory outputs, so every test can start with fresh ones
+func ResetOutputMemories() {
+	outputMemories.Lock()
+	defer outputMemories.Unlock()
+
+	outputMemories.instances = make(map[string]*OutputMemory)
 }
 
 func NewOutputMemory() *OutputMemory {
@@ -12,20 +46,60 @@ func NewOutputMemory() *OutputMemory {
 	}
 }
 
+// NewOutputMemoryLoopback creates a memory output which additionally publishes all written messages to the input
+func NewOutputMemoryLoopback(input *InMemoryInput) *OutputMemory {
+	output := NewOutputMemory()
+	output.loopback = input
+
+	return output
+}
+
 func (o *OutputMemory) WriteOne(ctx context.Context, msg *Message) error {
 	return o.Write(ctx, []*Message{msg})
 }
 
 func (o *OutputMemory) Write(ctx context.Context, batch []*Message) error {
+	o.lck.Lock()
 	o.messages = append(o.messages, batch...)
+	loopback := o.loopback
+	o.lck.Unlock()
+
+	if loopback != nil {
+		loopback.Publish(batch...)
+	}
+
 	return nil
 }
 
+// SetLoopback publishes all messages written from now on to the input
+func (o *OutputMemory) SetLoopback(input *InMemoryInput) {
+	o.lck.Lock()
+	defer o.lck.Unlock()
+
+	o.loopback = input
+}
+
+func (o *OutputMemory) Messages() []*Message {
+	o.lck.Lock()
+	defer o.lck.Unlock()
+
+	messages := make([]*Message, len(o.messages))
+	copy(messages, o.messages)
+
+	return messages
+}
+
 func (o *OutputMemory) Size() int {
+	o.lck.Lock()
+	defer o.lck.Unlock()
+
 	return len(o.messages)
 }
 
 func (o *OutputMemory) ContainsBody(body string) bool {
+	o.lck.Lock()
+	defer o.lck.Unlock()
+
 	for _, msg := range o.messages {
 		if msg.Body == body {
 			return true
@@ -0,0 +1,84 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+)
+
+const OutputTypeMulti = "multi"
+
+type MultiOutputSettings struct {
+	// FailFast stops writing to the remaining outputs after the first failed one. Otherwise all outputs are
+	// written and the errors are combined.
+	FailFast bool `cfg:"fail_fast"`
+}
+
+type multiOutput struct {
+	outputs  []Output
+	settings MultiOutputSettings
+}
+
+// NewMultiOutput writes every message to all of the given outputs in the order they are given
+func NewMultiOutput(settings MultiOutputSettings, outputs ...Output) Output {
+	return &multiOutput{
+		outputs:  outputs,
+		settings: settings,
+	}
+}
+
+func (o *multiOutput) WriteOne(ctx context.Context, msg *Message) error {
+	return o.Write(ctx, []*Message{msg})
+}
+
+func (o *multiOutput) Write(ctx context.Context, batch []*Message) error {
+	var result error
+
+	for i, output := range o.outputs {
+		err := output.Write(ctx, batch)
+
+		if err == nil {
+			continue
+		}
+
+		err = errors.Wrapf(err, "can not write to output %d", i)
+
+		if o.settings.FailFast {
+			return err
+		}
+
+		result = multierror.Append(result, err)
+	}
+
+	return result
+}
+
+type multiOutputConfiguration struct {
+	Outputs  []string `cfg:"outputs"`
+	FailFast bool     `cfg:"fail_fast"`
+}
+
+func newMultiOutputFromConfig(config cfg.Config, logger mon.Logger, name string) Output {
+	key := getConfigurableOutputKey(name)
+
+	configuration := multiOutputConfiguration{}
+	config.UnmarshalKey(key, &configuration)
+
+	if len(configuration.Outputs) == 0 {
+		logger.Fatalf(fmt.Errorf("no outputs configured"), "the multi output %s needs at least one output", name)
+		return nil
+	}
+
+	outputs := make([]Output, len(configuration.Outputs))
+
+	for i, outputName := range configuration.Outputs {
+		outputs[i] = NewConfigurableOutput(config, logger, outputName)
+	}
+
+	return NewMultiOutput(MultiOutputSettings{
+		FailFast: configuration.FailFast,
+	}, outputs...)
+}
@@ -0,0 +1,51 @@
+package stream_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/stream"
+	streamMocks "github.com/applike/gosoline/pkg/stream/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+func TestMultiOutput_Write(t *testing.T) {
+	first := stream.NewOutputMemory()
+	second := stream.NewOutputMemory()
+	output := stream.NewMultiOutput(stream.MultiOutputSettings{}, first, second)
+
+	err := output.WriteOne(context.Background(), &stream.Message{Body: "foobar"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, first.Size())
+	assert.Equal(t, 1, second.Size())
+}
+
+func TestMultiOutput_WriteBestEffort(t *testing.T) {
+	failing := new(streamMocks.Output)
+	failing.On("Write", mock.Anything, mock.Anything).Return(fmt.Errorf("write failed")).Once()
+
+	memory := stream.NewOutputMemory()
+	output := stream.NewMultiOutput(stream.MultiOutputSettings{}, failing, memory)
+
+	err := output.WriteOne(context.Background(), &stream.Message{Body: "foobar"})
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, memory.Size(), "the remaining outputs should be written")
+	failing.AssertExpectations(t)
+}
+
+func TestMultiOutput_WriteFailFast(t *testing.T) {
+	failing := new(streamMocks.Output)
+	failing.On("Write", mock.Anything, mock.Anything).Return(fmt.Errorf("write failed")).Once()
+
+	memory := stream.NewOutputMemory()
+	output := stream.NewMultiOutput(stream.MultiOutputSettings{FailFast: true}, failing, memory)
+
+	err := output.WriteOne(context.Background(), &stream.Message{Body: "foobar"})
+
+	assert.EqualError(t, err, "can not write to output 0: write failed")
+	assert.Equal(t, 0, memory.Size(), "the remaining outputs should be skipped")
+	failing.AssertExpectations(t)
+}
@@ -0,0 +1,140 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/applike/gosoline/pkg/redis"
+	"github.com/applike/gosoline/pkg/tracing"
+	baseRedis "github.com/go-redis/redis"
+	"time"
+)
+
+const (
+	metricNameRedisStreamOutputWrites = "StreamRedisStreamOutputWrites"
+)
+
+type RedisStreamOutputSettings struct {
+	cfg.AppId
+	ServerName string
+	Key        string
+	// MaxLen trims the stream to the given number of entries on every write, the stream isn't trimmed if not set.
+	// MaxLenApprox trims to at least MaxLen entries, which is a lot cheaper for redis.
+	MaxLen       int64
+	MaxLenApprox bool
+}
+
+type redisStreamOutput struct {
+	logger            mon.Logger
+	metricWriter      mon.MetricWriter
+	tracer            tracing.Tracer
+	client            redis.Client
+	settings          *RedisStreamOutputSettings
+	fullyQualifiedKey string
+}
+
+func NewRedisStreamOutput(config cfg.Config, logger mon.Logger, settings *RedisStreamOutputSettings) Output {
+	settings.PadFromConfig(config)
+
+	tracer := tracing.NewAwsTracer(config)
+	client := redis.GetClient(config, logger, settings.ServerName)
+
+	defaultMetrics := getRedisStreamOutputDefaultMetrics(settings.AppId, settings.Key)
+	mw := mon.NewMetricDaemonWriter(defaultMetrics...)
+
+	return NewRedisStreamOutputWithInterfaces(logger, mw, tracer, client, settings)
+}
+
+func NewRedisStreamOutputWithInterfaces(logger mon.Logger, mw mon.MetricWriter, tracer tracing.Tracer, client redis.Client, settings *RedisStreamOutputSettings) Output {
+	fullyQualifiedKey := redis.GetFullyQualifiedKey(settings.AppId, settings.Key)
+
+	return &redisStreamOutput{
+		logger:            logger,
+		metricWriter:      mw,
+		tracer:            tracer,
+		client:            client,
+		settings:          settings,
+		fullyQualifiedKey: fullyQualifiedKey,
+	}
+}
+
+func (o *redisStreamOutput) WriteOne(ctx context.Context, record *Message) error {
+	return o.Write(ctx, []*Message{record})
+}
+
+func (o *redisStreamOutput) Write(ctx context.Context, batch []*Message) error {
+	spanName := fmt.Sprintf("redis-stream-output-%v-%v-%v", o.settings.Family, o.settings.Application, o.settings.Key)
+
+	ctx, trans := o.tracer.StartSpanFromContext(ctx, spanName)
+	defer trans.Finish()
+
+	for _, msg := range batch {
+		msg.Trace = trans.GetTrace()
+	}
+
+	return o.addToStream(batch)
+}
+
+func (o *redisStreamOutput) addToStream(batch []*Message) error {
+	for i, msg := range batch {
+		data, err := msg.MarshalToString()
+
+		if err != nil {
+			return err
+		}
+
+		args := &baseRedis.XAddArgs{
+			Stream: o.fullyQualifiedKey,
+			Values: map[string]interface{}{
+				redisStreamMessageField: data,
+			},
+		}
+
+		if o.settings.MaxLenApprox {
+			args.MaxLenApprox = o.settings.MaxLen
+		} else {
+			args.MaxLen = o.settings.MaxLen
+		}
+
+		if _, err = o.client.XAdd(args); err != nil {
+			o.writeStreamWriteMetric(i)
+			return err
+		}
+	}
+
+	o.writeStreamWriteMetric(len(batch))
+
+	return nil
+}
+
+func (o *redisStreamOutput) writeStreamWriteMetric(length int) {
+	data := mon.MetricData{{
+		Priority:   mon.PriorityHigh,
+		Timestamp:  time.Now(),
+		MetricName: metricNameRedisStreamOutputWrites,
+		Dimensions: map[string]string{
+			"StreamName": o.fullyQualifiedKey,
+		},
+		Unit:  mon.UnitCount,
+		Value: float64(length),
+	}}
+
+	o.metricWriter.Write(data)
+}
+
+func getRedisStreamOutputDefaultMetrics(appId cfg.AppId, key string) mon.MetricData {
+	fullyQualifiedKey := redis.GetFullyQualifiedKey(appId, key)
+
+	return mon.MetricData{
+		{
+			Priority:   mon.PriorityHigh,
+			MetricName: metricNameRedisStreamOutputWrites,
+			Dimensions: map[string]string{
+				"StreamName": fullyQualifiedKey,
+			},
+			Unit:  mon.UnitCount,
+			Value: 0.0,
+		},
+	}
+}
@@ -0,0 +1,78 @@
+package stream_test
+
+import (
+	"context"
+	"errors"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/mon/mocks"
+	redisMocks "github.com/applike/gosoline/pkg/redis/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	"github.com/applike/gosoline/pkg/tracing"
+	baseRedis "github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+func buildRedisStreamOutput(maxLen int64, approx bool) (stream.Output, *redisMocks.Client) {
+	logger := mocks.NewLoggerMockedAll()
+	mw := mocks.NewMetricWriterMockedAll()
+	tracer := tracing.NewNoopTracer()
+	client := new(redisMocks.Client)
+
+	settings := &stream.RedisStreamOutputSettings{
+		AppId: cfg.AppId{
+			Project:     "mcoins",
+			Environment: "test",
+			Family:      "analytics",
+			Application: "app",
+		},
+		Key:          "my-stream",
+		MaxLen:       maxLen,
+		MaxLenApprox: approx,
+	}
+
+	return stream.NewRedisStreamOutputWithInterfaces(logger, mw, tracer, client, settings), client
+}
+
+func TestRedisStreamOutput_Write(t *testing.T) {
+	output, client := buildRedisStreamOutput(0, false)
+	client.On("XAdd", mock.MatchedBy(func(args *baseRedis.XAddArgs) bool {
+		return args.Stream == redisStreamTestKey && args.MaxLen == 0 && args.MaxLenApprox == 0 && args.Values["message"] != nil
+	})).Return("1-0", nil).Times(2)
+
+	batch := []*stream.Message{
+		{Body: "foo"},
+		{Body: "bar"},
+	}
+	err := output.Write(context.Background(), batch)
+
+	assert.NoError(t, err)
+	client.AssertExpectations(t)
+}
+
+func TestRedisStreamOutput_WriteMaxLen(t *testing.T) {
+	output, client := buildRedisStreamOutput(100, true)
+	client.On("XAdd", mock.MatchedBy(func(args *baseRedis.XAddArgs) bool {
+		return args.MaxLen == 0 && args.MaxLenApprox == 100
+	})).Return("1-0", nil).Once()
+
+	err := output.WriteOne(context.Background(), &stream.Message{Body: "foo"})
+
+	assert.NoError(t, err)
+	client.AssertExpectations(t)
+}
+
+func TestRedisStreamOutput_WriteError(t *testing.T) {
+	output, client := buildRedisStreamOutput(0, false)
+	client.On("XAdd", mock.Anything).Return("", errors.New("connection refused")).Once()
+
+	batch := []*stream.Message{
+		{Body: "foo"},
+		{Body: "bar"},
+	}
+	err := output.Write(context.Background(), batch)
+
+	assert.Error(t, err)
+	client.AssertExpectations(t)
+}
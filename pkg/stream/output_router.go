@@ -0,0 +1,129 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/cfg"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+const OutputTypeRouter = "router"
+
+// RouteFunc returns the route of a message, which is used to look up the output the message is written to
+type RouteFunc func(msg *Message) string
+
+type routerOutput struct {
+	route    RouteFunc
+	routes   map[string]Output
+	fallback Output
+}
+
+// NewRouterOutput writes every message to the output of its route. Messages with an unknown route are
+// written to the fallback output, which may be nil to fail on unknown routes.
+func NewRouterOutput(route RouteFunc, routes map[string]Output, fallback Output) Output {
+	return &routerOutput{
+		route:    route,
+		routes:   routes,
+		fallback: fallback,
+	}
+}
+
+// NewAttributeRouterOutput routes the messages by the value of the given attribute
+func NewAttributeRouterOutput(attribute string, routes map[string]Output, fallback Output) Output {
+	return NewRouterOutput(RouteByAttribute(attribute), routes, fallback)
+}
+
+// RouteByAttribute returns the value of the attribute as route or an empty route if the attribute is missing
+func RouteByAttribute(attribute string) RouteFunc {
+	return func(msg *Message) string {
+		value, ok := msg.Attributes[attribute]
+
+		if !ok || value == nil {
+			return ""
+		}
+
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+func (o *routerOutput) WriteOne(ctx context.Context, msg *Message) error {
+	return o.Write(ctx, []*Message{msg})
+}
+
+// Write keeps the order of the messages per route
+func (o *routerOutput) Write(ctx context.Context, batch []*Message) error {
+	var result error
+
+	routes := make([]string, 0)
+	batches := make(map[string][]*Message)
+
+	for _, msg := range batch {
+		route := o.route(msg)
+
+		if _, ok := batches[route]; !ok {
+			routes = append(routes, route)
+		}
+
+		batches[route] = append(batches[route], msg)
+	}
+
+	for _, route := range routes {
+		output, ok := o.routes[route]
+
+		if !ok {
+			output = o.fallback
+		}
+
+		if output == nil {
+			result = multierror.Append(result, fmt.Errorf("there is no output for the route %s", route))
+			continue
+		}
+
+		if err := output.Write(ctx, batches[route]); err != nil {
+			result = multierror.Append(result, errors.Wrapf(err, "can not write to the output of the route %s", route))
+		}
+	}
+
+	return result
+}
+
+type routerOutputConfiguration struct {
+	Attribute string `cfg:"attribute"`
+	// Routes maps the attribute values to the names of the outputs. The config lowercases the keys of maps,
+	// so the values are matched case insensitive.
+	Routes   map[string]string `cfg:"routes"`
+	Fallback string            `cfg:"fallback"`
+}
+
+func newRouterOutputFromConfig(config cfg.Config, logger mon.Logger, name string) Output {
+	key := getConfigurableOutputKey(name)
+
+	configuration := routerOutputConfiguration{}
+	config.UnmarshalKey(key, &configuration)
+
+	if configuration.Attribute == "" {
+		logger.Fatalf(fmt.Errorf("no attribute configured"), "the router output %s needs an attribute to route by", name)
+		return nil
+	}
+
+	var fallback Output
+	routes := make(map[string]Output)
+
+	for route, outputName := range configuration.Routes {
+		routes[strings.ToLower(route)] = NewConfigurableOutput(config, logger, outputName)
+	}
+
+	if configuration.Fallback != "" {
+		fallback = NewConfigurableOutput(config, logger, configuration.Fallback)
+	}
+
+	routeByAttribute := RouteByAttribute(configuration.Attribute)
+	route := func(msg *Message) string {
+		return strings.ToLower(routeByAttribute(msg))
+	}
+
+	return NewRouterOutput(route, routes, fallback)
+}
@@ -0,0 +1,46 @@
+package stream_test
+
+import (
+	"context"
+	"github.com/applike/gosoline/pkg/stream"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRouterOutput_Write(t *testing.T) {
+	created := stream.NewOutputMemory()
+	fallback := stream.NewOutputMemory()
+
+	output := stream.NewAttributeRouterOutput("type", map[string]stream.Output{
+		"created": created,
+	}, fallback)
+
+	createdMsg := &stream.Message{Attributes: map[string]interface{}{"type": "created"}}
+	deletedMsg := &stream.Message{Attributes: map[string]interface{}{"type": "deleted"}}
+	untypedMsg := &stream.Message{Attributes: map[string]interface{}{}}
+
+	err := output.Write(context.Background(), []*stream.Message{createdMsg, deletedMsg, untypedMsg})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []*stream.Message{createdMsg}, created.Messages())
+	assert.Equal(t, []*stream.Message{deletedMsg, untypedMsg}, fallback.Messages())
+}
+
+func TestRouterOutput_WriteUnknownRoute(t *testing.T) {
+	even := stream.NewOutputMemory()
+
+	output := stream.NewRouterOutput(func(msg *stream.Message) string {
+		if len(msg.Body)%2 == 0 {
+			return "even"
+		}
+
+		return "odd"
+	}, map[string]stream.Output{
+		"even": even,
+	}, nil)
+
+	err := output.Write(context.Background(), []*stream.Message{{Body: "ab"}, {Body: "abc"}})
+
+	assert.Error(t, err, "there is no output for odd messages")
+	assert.Equal(t, 1, even.Size(), "the known routes should be written anyway")
+}
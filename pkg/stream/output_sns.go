@@ -12,14 +12,16 @@ import (
 type SnsOutputSettings struct {
 	cfg.AppId
 	TopicId string
+	Offload OffloadSettings
 }
 
 type snsOutput struct {
 	logger mon.Logger
 	tracer tracing.Tracer
 
-	topic    sns.Topic
-	settings SnsOutputSettings
+	topic     sns.Topic
+	offloader *MessageOffloader
+	settings  SnsOutputSettings
 }
 
 func NewSnsOutput(config cfg.Config, logger mon.Logger, s SnsOutputSettings) Output {
@@ -31,11 +33,16 @@ func NewSnsOutput(config cfg.Config, logger mon.Logger, s SnsOutputSettings) Out
 	})
 
 	tracer := tracing.NewAwsTracer(config)
+	output := NewSnsOutputWithInterfaces(logger, tracer, topic, s)
 
-	return NewSnsOutputWithInterfaces(logger, tracer, topic, s)
+	if s.Offload.Enabled {
+		output.SetMessageOffloader(NewMessageOffloader(config, logger, s.Offload))
+	}
+
+	return output
 }
 
-func NewSnsOutputWithInterfaces(logger mon.Logger, tracer tracing.Tracer, topic sns.Topic, s SnsOutputSettings) Output {
+func NewSnsOutputWithInterfaces(logger mon.Logger, tracer tracing.Tracer, topic sns.Topic, s SnsOutputSettings) *snsOutput {
 	return &snsOutput{
 		logger:   logger,
 		tracer:   tracer,
@@ -65,7 +72,7 @@ func (o *snsOutput) publishToTopic(ctx context.Context, batch []*Message) error
 	errors := make([]error, 0)
 
 	for _, msg := range batch {
-		body, err := msg.MarshalToString()
+		body, err := o.marshalToString(msg)
 
 		if err != nil {
 			errors = append(errors, err)
@@ -86,3 +93,16 @@ func (o *snsOutput) publishToTopic(ctx context.Context, batch []*Message) error
 
 	return nil
 }
+
+func (o *snsOutput) marshalToString(msg *Message) (string, error) {
+	if o.offloader == nil {
+		return msg.MarshalToString()
+	}
+
+	return o.offloader.MarshalToString(msg)
+}
+
+// SetMessageOffloader writes the bodies of messages exceeding the size limit of sns to the blob store
+func (o *snsOutput) SetMessageOffloader(offloader *MessageOffloader) {
+	o.offloader = offloader
+}
@@ -0,0 +1,52 @@
+package stream_test
+
+import (
+	"context"
+	"encoding/json"
+	monMocks "github.com/applike/gosoline/pkg/mon/mocks"
+	"github.com/applike/gosoline/pkg/sns"
+	snsMocks "github.com/applike/gosoline/pkg/sns/mocks"
+	"github.com/applike/gosoline/pkg/stream"
+	"github.com/applike/gosoline/pkg/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func TestSnsOutput_ProducedAtRoundTrip(t *testing.T) {
+	logger := monMocks.NewLoggerMockedAll()
+	tracer := tracing.NewNoopTracer()
+
+	var published string
+
+	topic := new(snsMocks.Topic)
+	topic.On("Publish", mock.Anything, mock.AnythingOfType("*string")).Run(func(args mock.Arguments) {
+		published = *args.Get(1).(*string)
+	}).Return(nil).Once()
+
+	msg, err := stream.NewMessageBuilder().WithBody("foobar").GetMessage()
+	assert.NoError(t, err)
+
+	output := stream.NewSnsOutputWithInterfaces(logger, tracer, topic, stream.SnsOutputSettings{})
+	err = output.WriteOne(context.Background(), msg)
+	assert.NoError(t, err)
+	topic.AssertExpectations(t)
+
+	// sns wraps the published message into a notification before delivering it to the subscribed queue
+	notification, err := json.Marshal(sns.Message{
+		Type:      "Notification",
+		MessageId: "b0d6b7a2-5c4b-4a3c-9f1e-4f3a7a4f2c11",
+		Message:   published,
+	})
+	assert.NoError(t, err)
+
+	data := string(notification)
+	received, err := stream.SnsUnmarshaler(&data)
+	assert.NoError(t, err)
+
+	age, ok := stream.GetMessageAge(received)
+
+	assert.True(t, ok, "the producer timestamp should survive the round trip through sns")
+	assert.True(t, age >= 0 && age < time.Minute, "the message should have been produced just now")
+}
@@ -20,13 +20,15 @@ type SqsOutputSettings struct {
 	QueueId           string
 	VisibilityTimeout int
 	RedrivePolicy     sqs.RedrivePolicy
+	Offload           OffloadSettings
 }
 
 type sqsOutput struct {
-	logger   mon.Logger
-	tracer   tracing.Tracer
-	queue    sqs.Queue
-	settings SqsOutputSettings
+	logger    mon.Logger
+	tracer    tracing.Tracer
+	queue     sqs.Queue
+	offloader *MessageOffloader
+	settings  SqsOutputSettings
 }
 
 func NewSqsOutput(config cfg.Config, logger mon.Logger, s SqsOutputSettings) Output {
@@ -40,11 +42,16 @@ func NewSqsOutput(config cfg.Config, logger mon.Logger, s SqsOutputSettings) Out
 	})
 
 	tracer := tracing.NewAwsTracer(config)
+	output := NewSqsOutputWithInterfaces(logger, tracer, queue, s)
 
-	return NewSqsOutputWithInterfaces(logger, tracer, queue, s)
+	if s.Offload.Enabled {
+		output.SetMessageOffloader(NewMessageOffloader(config, logger, s.Offload))
+	}
+
+	return output
 }
 
-func NewSqsOutputWithInterfaces(logger mon.Logger, tracer tracing.Tracer, queue sqs.Queue, s SqsOutputSettings) Output {
+func NewSqsOutputWithInterfaces(logger mon.Logger, tracer tracing.Tracer, queue sqs.Queue, s SqsOutputSettings) *sqsOutput {
 	return &sqsOutput{
 		logger:   logger,
 		tracer:   tracer,
@@ -145,7 +152,7 @@ func (o *sqsOutput) buildSqsMessage(msg *Message) (*sqs.Message, error) {
 		}
 	}
 
-	body, err := msg.MarshalToString()
+	body, err := o.marshalToString(msg)
 
 	if err != nil {
 		return nil, err
@@ -159,3 +166,16 @@ func (o *sqsOutput) buildSqsMessage(msg *Message) (*sqs.Message, error) {
 
 	return sqsMessage, nil
 }
+
+func (o *sqsOutput) marshalToString(msg *Message) (string, error) {
+	if o.offloader == nil {
+		return msg.MarshalToString()
+	}
+
+	return o.offloader.MarshalToString(msg)
+}
+
+// SetMessageOffloader writes the bodies of messages exceeding the size limit of sqs to the blob store
+func (o *sqsOutput) SetMessageOffloader(offloader *MessageOffloader) {
+	o.offloader = offloader
+}
@@ -11,22 +11,19 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"testing"
+	"time"
 )
 
 func TestSqsOutput_WriteOne(t *testing.T) {
 	logger := monMocks.NewLoggerMockedAll()
 	tracer := tracing.NewNoopTracer()
 
-	expectedBody := `{"trace":{"traceId":"","id":"","parentId":"","sampled":false},"attributes":{"sqsDelaySeconds":45},"body":"{\"Foo\":\"bar\"}"}`
-	expectedSqsMessages := []*sqs.Message{
-		{
-			DelaySeconds: mdl.Int64(45),
-			Body:         mdl.String(expectedBody),
-		},
-	}
+	var sqsMessages []*sqs.Message
 
 	queue := new(sqsMocks.Queue)
-	queue.On("SendBatch", mock.AnythingOfType("*context.emptyCtx"), expectedSqsMessages).Return(nil)
+	queue.On("SendBatch", mock.Anything, mock.AnythingOfType("[]*sqs.Message")).Run(func(args mock.Arguments) {
+		sqsMessages = args.Get(1).([]*sqs.Message)
+	}).Return(nil).Once()
 
 	msg, err := BuildSqsTestMessage()
 	assert.NoError(t, err)
@@ -35,4 +32,42 @@ func TestSqsOutput_WriteOne(t *testing.T) {
 	err = output.WriteOne(context.Background(), msg)
 
 	assert.NoError(t, err)
+	queue.AssertExpectations(t)
+
+	assert.Len(t, sqsMessages, 1)
+	assert.Equal(t, mdl.Int64(45), sqsMessages[0].DelaySeconds)
+
+	written, err := stream.BasicUnmarshaler(sqsMessages[0].Body)
+	assert.NoError(t, err)
+
+	assert.Equal(t, `{"Foo":"bar"}`, written.Body)
+	assert.Equal(t, float64(45), written.Attributes["sqsDelaySeconds"])
+	assert.Contains(t, written.Attributes, stream.AttributeProducedAt)
+}
+
+func TestSqsOutput_ProducedAtRoundTrip(t *testing.T) {
+	logger := monMocks.NewLoggerMockedAll()
+	tracer := tracing.NewNoopTracer()
+
+	var body *string
+
+	queue := new(sqsMocks.Queue)
+	queue.On("SendBatch", mock.Anything, mock.AnythingOfType("[]*sqs.Message")).Run(func(args mock.Arguments) {
+		body = args.Get(1).([]*sqs.Message)[0].Body
+	}).Return(nil).Once()
+
+	msg, err := BuildSqsTestMessage()
+	assert.NoError(t, err)
+
+	output := stream.NewSqsOutputWithInterfaces(logger, tracer, queue, stream.SqsOutputSettings{})
+	err = output.WriteOne(context.Background(), msg)
+	assert.NoError(t, err)
+
+	received, err := stream.BasicUnmarshaler(body)
+	assert.NoError(t, err)
+
+	age, ok := stream.GetMessageAge(received)
+
+	assert.True(t, ok, "the producer timestamp should survive the round trip through sqs")
+	assert.True(t, age >= 0 && age < time.Minute, "the message should have been produced just now")
 }
@@ -13,6 +13,8 @@ import (
 const MetricNamePipelineReceivedCount = "PipelineReceivedCount"
 const MetricNamePipelineProcessedCount = "PipelineProcessedCount"
 
+const pipelineDefaultInput = "pipeline"
+
 //go:generate mockery -name PipelineCallback
 type PipelineCallback interface {
 	Boot(config cfg.Config, logger mon.Logger) error
@@ -20,31 +22,38 @@ type PipelineCallback interface {
 }
 
 type PipelineSettings struct {
+	// Input is the name of the configurable input the pipeline reads from, defaults to pipeline
+	Input     string
 	Interval  time.Duration
 	BatchSize int
+	// Stages configure the error policy of the stage at the same index, stages without settings nack failed messages
+	Stages []PipelineStageSettings
 }
 
 type Pipeline struct {
 	kernel.EssentialModule
 	ConsumerAcknowledge
 
-	logger         mon.Logger
-	metric         mon.MetricWriter
-	defaultMetrics mon.MetricData
-	cfn            coffin.Coffin
-	lck            sync.Mutex
-	output         Output
-	ticker         *time.Ticker
-	batch          []*Message
-	stages         []PipelineCallback
-	settings       *PipelineSettings
+	logger           mon.Logger
+	metric           mon.MetricWriter
+	defaultMetrics   mon.MetricData
+	cfn              coffin.Coffin
+	lck              sync.Mutex
+	output           Output
+	deadLetterOutput Output
+	ticker           *time.Ticker
+	batch            []*Message
+	stages           []PipelineCallback
+	middlewares      []PipelineMiddleware
+	handlers         []ProcessHandler
+	settings         *PipelineSettings
 }
 
 func NewPipeline(stages ...PipelineCallback) *Pipeline {
 	return &Pipeline{
 		cfn:            coffin.New(),
 		stages:         stages,
-		defaultMetrics: getDefaultPipelineMetrics(),
+		defaultMetrics: append(getDefaultPipelineMetrics(), getDefaultPipelineStageMetrics(len(stages))...),
 	}
 }
 
@@ -59,18 +68,40 @@ func (p *Pipeline) Boot(config cfg.Config, logger mon.Logger) error {
 
 	metric := mon.NewMetricDaemonWriter(p.defaultMetrics...)
 
-	input := NewConfigurableInput(config, logger, "pipeline")
-	output := NewConfigurableOutput(config, logger, "pipeline")
-
 	settings := &PipelineSettings{
+		Input:     pipelineDefaultInput,
 		Interval:  config.GetDuration("pipeline_interval") * time.Second,
 		BatchSize: config.GetInt("pipeline_batch_size"),
 	}
 
+	if config.IsSet("pipeline_input") {
+		settings.Input = config.GetString("pipeline_input")
+	}
+
+	input := NewConfigurableInput(config, logger, settings.Input)
+	output := NewConfigurableOutput(config, logger, "pipeline")
+	p.Use(readPipelineMiddlewares(config, logger, "pipeline")...)
+
+	if config.IsSet("pipeline_stages") {
+		config.UnmarshalKey("pipeline_stages", &settings.Stages)
+	}
+
+	if config.IsSet("output_pipeline_dead_letter_type") {
+		p.SetDeadLetterOutput(NewConfigurableOutput(config, logger, "pipeline_dead_letter"))
+	}
+
 	return p.BootWithInterfaces(logger, metric, input, output, settings)
 }
 
 func (p *Pipeline) BootWithInterfaces(logger mon.Logger, metric mon.MetricWriter, input Input, output Output, settings *PipelineSettings) error {
+	if err := validatePipelineStageSettings(settings.Stages); err != nil {
+		return err
+	}
+
+	if settings.Input == "" {
+		settings.Input = pipelineDefaultInput
+	}
+
 	p.logger = logger
 	p.metric = metric
 	p.input = input
@@ -78,10 +109,25 @@ func (p *Pipeline) BootWithInterfaces(logger mon.Logger, metric mon.MetricWriter
 	p.ticker = time.NewTicker(settings.Interval)
 	p.batch = make([]*Message, 0, settings.BatchSize)
 	p.settings = settings
+	p.handlers = make([]ProcessHandler, len(p.stages))
+
+	for i, stage := range p.stages {
+		p.handlers[i] = chainPipelineMiddlewares(stage.Process, p.middlewares)
+	}
 
 	return nil
 }
 
+// Use adds middlewares around every stage, it has to be called before the pipeline is booted
+func (p *Pipeline) Use(middlewares ...PipelineMiddleware) {
+	p.middlewares = append(p.middlewares, middlewares...)
+}
+
+// SetDeadLetterOutput sets the output for messages which failed in a stage with the dead letter or retry error policy
+func (p *Pipeline) SetDeadLetterOutput(output Output) {
+	p.deadLetterOutput = output
+}
+
 func (p *Pipeline) SetDefaultMetrics(defaultMetrics mon.MetricData) {
 	p.defaultMetrics = defaultMetrics
 }
@@ -118,6 +164,7 @@ func (p *Pipeline) read(ctx context.Context) error {
 				return nil
 			}
 
+			writeMessageAgeMetric(p.metric, p.settings.Input, msg)
 			p.batch = append(p.batch, msg)
 
 		case <-p.ticker.C:
@@ -157,34 +204,40 @@ func (p *Pipeline) process(ctx context.Context, force bool) {
 
 	p.ticker.Stop()
 
-	var err error
-
+	batch := newPipelineBatch(p.batch)
 	msg := p.batch
 
-	for _, stage := range p.stages {
-		msg, err = stage.Process(ctx, msg)
-
-		if err != nil {
-			p.logger.Error(err, "could not process the batch")
+	for stage := range p.handlers {
+		msg = p.runStage(ctx, stage, msg, batch)
 
-			return
+		if batch.nackAll {
+			break
 		}
 	}
 
-	err = p.output.Write(ctx, msg)
-
-	if err != nil {
-		p.logger.Error(err, "could not write messages to output")
+	// the whole batch is read again, so nothing is written to not write the other messages twice
+	if batch.nackAll {
+		p.logger.Warnf("returning all %d messages of the batch to the input, as a failed message can't be traced back to its origin", batchSize)
+		p.nackMessages(ctx, p.batch)
 
 		return
 	}
 
-	processedCount := len(p.batch)
+	if len(msg) > 0 {
+		if err := p.output.Write(ctx, msg); err != nil {
+			p.logger.Error(err, "could not write messages to output")
+			p.nackMessages(ctx, p.batch)
 
-	for _, msg := range p.batch {
-		p.Acknowledge(ctx, msg)
+			return
+		}
 	}
 
+	acked, nacked := batch.split()
+	processedCount := len(acked)
+
+	p.AcknowledgeBatch(ctx, acked)
+	p.nackMessages(ctx, nacked)
+
 	p.logger.Infof("pipeline processed %d of %d messages", processedCount, batchSize)
 	p.metric.WriteOne(&mon.MetricDatum{
 		MetricName: MetricNamePipelineProcessedCount,
@@ -207,3 +260,9 @@ func getDefaultPipelineMetrics() mon.MetricData {
 		},
 	}
 }
+
+func (p *Pipeline) nackMessages(ctx context.Context, msgs []*Message) {
+	for _, msg := range msgs {
+		p.Nack(ctx, msg, NackDefaultDelay)
+	}
+}
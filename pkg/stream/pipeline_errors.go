@@ -0,0 +1,343 @@
+package stream
+
+import (
+	"context"
+	"fmt"
+	"github.com/applike/gosoline/pkg/mon"
+	"github.com/cenkalti/backoff"
+	"github.com/pkg/errors"
+	"strconv"
+	"time"
+)
+
+const (
+	PipelineErrorPolicyNack       = "nack"
+	PipelineErrorPolicyDrop       = "drop"
+	PipelineErrorPolicyRetry      = "retry"
+	PipelineErrorPolicyDeadLetter = "deadLetter"
+)
+
+const (
+	MetricNamePipelineStageLatency      = "PipelineStageLatency"
+	MetricNamePipelineStageFailureCount = "PipelineStageFailureCount"
+)
+
+const (
+	pipelineDefaultMaxAttempts = 3
+	pipelineDefaultRetryWait   = 100 * time.Millisecond
+)
+
+// PipelineStageSettings decide what happens to the messages a stage failed to process:
+//   - nack returns them to the input, so they are delivered again (default)
+//   - drop logs and acknowledges them
+//   - retry processes them again until MaxAttempts is reached
+//   - deadLetter writes them to the dead letter output of the pipeline and acknowledges them
+//
+// Messages which still fail after the last retry are written to the dead letter output if the pipeline has one,
+// otherwise they are returned to the input.
+type PipelineStageSettings struct {
+	ErrorPolicy string `cfg:"error_policy"`
+	MaxAttempts int    `cfg:"max_attempts"`
+}
+
+// PipelineMessageError is the failure of a single message of the batch
+type PipelineMessageError struct {
+	Msg *Message
+	Err error
+}
+
+// PipelineMessageErrors is returned by stages which failed to process some of the messages of the batch. The stage
+// returns the messages it processed successfully next to it, the failed messages are handled by the error policy
+// of the stage. Any other error of a stage fails all messages the stage received.
+type PipelineMessageErrors struct {
+	failures []*PipelineMessageError
+}
+
+func NewPipelineMessageErrors() *PipelineMessageErrors {
+	return &PipelineMessageErrors{
+		failures: make([]*PipelineMessageError, 0),
+	}
+}
+
+func (e *PipelineMessageErrors) Add(msg *Message, err error) {
+	e.failures = append(e.failures, &PipelineMessageError{
+		Msg: msg,
+		Err: err,
+	})
+}
+
+func (e *PipelineMessageErrors) Failures() []*PipelineMessageError {
+	return e.failures
+}
+
+// ErrorOrNil returns nil if no message failed, so stages can always return it as their error
+func (e *PipelineMessageErrors) ErrorOrNil() error {
+	if e == nil || len(e.failures) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+func (e *PipelineMessageErrors) Error() string {
+	if len(e.failures) == 0 {
+		return "no messages failed"
+	}
+
+	return fmt.Sprintf("%d messages failed, the first with: %s", len(e.failures), e.failures[0].Err.Error())
+}
+
+func getPipelineMessageErrors(messages []*Message, err error) []*PipelineMessageError {
+	if msgErrs, ok := errors.Cause(err).(*PipelineMessageErrors); ok {
+		return msgErrs.Failures()
+	}
+
+	failures := make([]*PipelineMessageError, len(messages))
+
+	for i, msg := range messages {
+		failures[i] = &PipelineMessageError{
+			Msg: msg,
+			Err: err,
+		}
+	}
+
+	return failures
+}
+
+// pipelineBatch keeps track of the messages read from the input which have to be returned to it
+type pipelineBatch struct {
+	received []*Message
+	isNacked map[*Message]bool
+	nackAll  bool
+}
+
+func newPipelineBatch(received []*Message) *pipelineBatch {
+	return &pipelineBatch{
+		received: received,
+		isNacked: make(map[*Message]bool),
+	}
+}
+
+// nack returns the message to the input. Messages created by a stage can't be traced back to the messages they
+// originate from, so the whole batch is returned in that case and nothing of it is written to the output.
+func (b *pipelineBatch) nack(msg *Message) {
+	for _, received := range b.received {
+		if received == msg {
+			b.isNacked[msg] = true
+			return
+		}
+	}
+
+	b.nackAll = true
+}
+
+func (b *pipelineBatch) split() (acked []*Message, nacked []*Message) {
+	if b.nackAll {
+		return nil, b.received
+	}
+
+	acked = make([]*Message, 0, len(b.received))
+	nacked = make([]*Message, 0, len(b.isNacked))
+
+	for _, msg := range b.received {
+		if b.isNacked[msg] {
+			nacked = append(nacked, msg)
+			continue
+		}
+
+		acked = append(acked, msg)
+	}
+
+	return acked, nacked
+}
+
+func (p *Pipeline) getStageSettings(stage int) PipelineStageSettings {
+	settings := PipelineStageSettings{}
+
+	if stage < len(p.settings.Stages) {
+		settings = p.settings.Stages[stage]
+	}
+
+	if settings.ErrorPolicy == "" {
+		settings.ErrorPolicy = PipelineErrorPolicyNack
+	}
+
+	if settings.MaxAttempts <= 0 {
+		settings.MaxAttempts = pipelineDefaultMaxAttempts
+	}
+
+	return settings
+}
+
+func validatePipelineStageSettings(stages []PipelineStageSettings) error {
+	for i, stage := range stages {
+		switch stage.ErrorPolicy {
+		case "", PipelineErrorPolicyNack, PipelineErrorPolicyDrop, PipelineErrorPolicyRetry, PipelineErrorPolicyDeadLetter:
+		default:
+			return fmt.Errorf("unknown error policy %s of the pipeline stage %d", stage.ErrorPolicy, i)
+		}
+	}
+
+	return nil
+}
+
+// runStage processes the messages with the handler of the stage and hands the failed messages to the error policy
+func (p *Pipeline) runStage(ctx context.Context, stage int, messages []*Message, batch *pipelineBatch) []*Message {
+	settings := p.getStageSettings(stage)
+	handler := p.handlers[stage]
+
+	start := time.Now()
+	processed, failures := p.callStage(ctx, handler, messages)
+
+	if len(failures) > 0 && settings.ErrorPolicy == PipelineErrorPolicyRetry {
+		var retried []*Message
+		retried, failures = p.retryStage(ctx, stage, handler, failures, settings.MaxAttempts)
+		processed = append(processed, retried...)
+	}
+
+	p.writeStageMetrics(stage, start, len(failures))
+
+	if len(failures) == 0 {
+		return processed
+	}
+
+	p.logger.WithContext(ctx).WithFields(mon.Fields{
+		"stage":    stage,
+		"policy":   settings.ErrorPolicy,
+		"received": len(messages),
+		"failed":   len(failures),
+	}).Warnf("the pipeline stage %d failed to process %d messages: %s", stage, len(failures), failures[0].Err.Error())
+
+	switch settings.ErrorPolicy {
+	case PipelineErrorPolicyDrop:
+	case PipelineErrorPolicyDeadLetter, PipelineErrorPolicyRetry:
+		p.deadLetter(ctx, failures, batch)
+	default:
+		for _, failure := range failures {
+			batch.nack(failure.Msg)
+		}
+	}
+
+	return processed
+}
+
+func (p *Pipeline) callStage(ctx context.Context, handler ProcessHandler, messages []*Message) ([]*Message, []*PipelineMessageError) {
+	processed, err := handler(ctx, messages)
+
+	if err == nil {
+		return processed, nil
+	}
+
+	if _, ok := errors.Cause(err).(*PipelineMessageErrors); !ok {
+		processed = nil
+	}
+
+	return processed, getPipelineMessageErrors(messages, err)
+}
+
+func (p *Pipeline) retryStage(ctx context.Context, stage int, handler ProcessHandler, failures []*PipelineMessageError, maxAttempts int) ([]*Message, []*PipelineMessageError) {
+	backoffConfig := backoff.NewExponentialBackOff()
+	backoffConfig.InitialInterval = pipelineDefaultRetryWait
+	retry := backoff.WithMaxRetries(backoffConfig, uint64(maxAttempts-1))
+
+	processed := make([]*Message, 0)
+
+	for {
+		wait := retry.NextBackOff()
+
+		if wait == backoff.Stop {
+			return processed, failures
+		}
+
+		select {
+		case <-ctx.Done():
+			return processed, failures
+		case <-time.After(wait):
+		}
+
+		messages := make([]*Message, len(failures))
+		for i, failure := range failures {
+			messages[i] = failure.Msg
+		}
+
+		p.logger.WithContext(ctx).Infof("retrying %d messages of the pipeline stage %d", len(messages), stage)
+
+		var retried []*Message
+		retried, failures = p.callStage(ctx, handler, messages)
+		processed = append(processed, retried...)
+
+		if len(failures) == 0 {
+			return processed, nil
+		}
+	}
+}
+
+// deadLetter writes the failed messages to the dead letter output, they are returned to the input if they can't be written
+func (p *Pipeline) deadLetter(ctx context.Context, failures []*PipelineMessageError, batch *pipelineBatch) {
+	messages := make([]*Message, len(failures))
+	for i, failure := range failures {
+		messages[i] = failure.Msg
+	}
+
+	if p.deadLetterOutput == nil {
+		for _, msg := range messages {
+			batch.nack(msg)
+		}
+
+		return
+	}
+
+	if err := p.deadLetterOutput.Write(ctx, messages); err != nil {
+		p.logger.WithContext(ctx).Error(err, "could not write the failed messages to the dead letter output")
+
+		for _, msg := range messages {
+			batch.nack(msg)
+		}
+	}
+}
+
+func (p *Pipeline) writeStageMetrics(stage int, start time.Time, failed int) {
+	dimensions := map[string]string{
+		"Stage": strconv.Itoa(stage),
+	}
+
+	p.metric.WriteOne(&mon.MetricDatum{
+		MetricName: MetricNamePipelineStageLatency,
+		Dimensions: dimensions,
+		Unit:       mon.UnitMilliseconds,
+		Value:      float64(time.Since(start).Nanoseconds()) / float64(time.Millisecond),
+	})
+
+	p.metric.WriteOne(&mon.MetricDatum{
+		MetricName: MetricNamePipelineStageFailureCount,
+		Dimensions: dimensions,
+		Unit:       mon.UnitCount,
+		Value:      float64(failed),
+	})
+}
+
+func getDefaultPipelineStageMetrics(stages int) mon.MetricData {
+	defaults := mon.MetricData{}
+
+	for i := 0; i < stages; i++ {
+		dimensions := map[string]string{
+			"Stage": strconv.Itoa(i),
+		}
+
+		defaults = append(defaults, &mon.MetricDatum{
+			Priority:   mon.PriorityHigh,
+			MetricName: MetricNamePipelineStageLatency,
+			Dimensions: dimensions,
+			Unit:       mon.UnitMilliseconds,
+			Value:      0.0,
+		}, &mon.MetricDatum{
+			Priority:   mon.PriorityHigh,
+			MetricName: MetricNamePipelineStageFailureCount,
+			Dimensions: dimensions,
+			Unit:       mon.UnitCount,
+			Value:      0.0,
+		})
+	}
+
+	return defaults
+}
@@ -2,11 +2,13 @@ package stream_test
 
 import (
 	"context"
+	"fmt"
 	"github.com/applike/gosoline/pkg/cfg"
 	"github.com/applike/gosoline/pkg/mon"
 	"github.com/applike/gosoline/pkg/mon/mocks"
 	"github.com/applike/gosoline/pkg/stream"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"testing"
 	"time"
 )
@@ -57,3 +59,227 @@ func TestPipeline_RunBatchSize(t *testing.T) {
 		BatchSize: 1,
 	}, context.Background())
 }
+
+type failingCallback struct {
+	failures int
+	attempts int
+}
+
+func (c *failingCallback) Boot(config cfg.Config, logger mon.Logger) error {
+	return nil
+}
+
+func (c *failingCallback) Process(ctx context.Context, messages []*stream.Message) ([]*stream.Message, error) {
+	processed := make([]*stream.Message, 0, len(messages))
+	errs := stream.NewPipelineMessageErrors()
+
+	for _, msg := range messages {
+		if msg.Body != "poison" {
+			processed = append(processed, msg)
+			continue
+		}
+
+		c.attempts++
+
+		if c.attempts <= c.failures {
+			errs.Add(msg, fmt.Errorf("can not process poison"))
+			continue
+		}
+
+		processed = append(processed, msg)
+	}
+
+	return processed, errs.ErrorOrNil()
+}
+
+func runFailingPipeline(t *testing.T, callback stream.PipelineCallback, stage stream.PipelineStageSettings, deadLetter stream.Output) (*stream.InMemoryInput, *stream.OutputMemory, []*stream.Message) {
+	logger := mocks.NewLoggerMockedAll()
+	metric := mocks.NewMetricWriterMockedAll()
+
+	messages := []*stream.Message{
+		{Body: "foo"},
+		{Body: "poison"},
+	}
+
+	input := stream.NewInMemoryInput(&stream.InMemorySettings{})
+	input.Publish(messages...)
+	input.Stop()
+
+	output := stream.NewOutputMemory()
+
+	pipe := stream.NewPipeline(callback)
+	pipe.SetDeadLetterOutput(deadLetter)
+
+	err := pipe.BootWithInterfaces(logger, metric, input, output, &stream.PipelineSettings{
+		Interval:  time.Hour,
+		BatchSize: 2,
+		Stages:    []stream.PipelineStageSettings{stage},
+	})
+	assert.NoError(t, err, "the pipeline should boot without an error")
+
+	err = pipe.Run(context.Background())
+	assert.NoError(t, err, "the pipeline should run without an error")
+
+	return input, output, messages
+}
+
+func TestPipeline_MessageAgeMetric(t *testing.T) {
+	logger := mocks.NewLoggerMockedAll()
+	metric := mocks.NewMetricWriterMockedAll()
+
+	input := stream.NewInMemoryInput(&stream.InMemorySettings{})
+	input.Publish(&stream.Message{
+		Attributes: map[string]interface{}{
+			stream.AttributeProducedAt: time.Now().UnixNano() / int64(time.Millisecond),
+		},
+		Body: "foo",
+	})
+	input.Stop()
+
+	pipe := stream.NewPipeline(&callback{})
+
+	err := pipe.BootWithInterfaces(logger, metric, input, stream.NewOutputMemory(), &stream.PipelineSettings{
+		Input:     "events",
+		Interval:  time.Hour,
+		BatchSize: 1,
+	})
+	assert.NoError(t, err, "the pipeline should boot without an error")
+
+	err = pipe.Run(context.Background())
+	assert.NoError(t, err, "the pipeline should run without an error")
+
+	metric.AssertCalled(t, "WriteOne", mock.MatchedBy(func(datum *mon.MetricDatum) bool {
+		return datum.MetricName == "StreamMessageAge" && datum.Dimensions["Input"] == "events"
+	}))
+}
+
+func TestPipeline_ErrorPolicyNack(t *testing.T) {
+	input, output, messages := runFailingPipeline(t, &failingCallback{failures: 1}, stream.PipelineStageSettings{}, nil)
+
+	assert.Equal(t, 1, output.Size())
+	assert.True(t, output.ContainsBody("foo"))
+	assert.Equal(t, messages[:1], input.Acks())
+	assert.Equal(t, messages[1:], input.Nacks())
+}
+
+func TestPipeline_ErrorPolicyDrop(t *testing.T) {
+	input, output, messages := runFailingPipeline(t, &failingCallback{failures: 1}, stream.PipelineStageSettings{
+		ErrorPolicy: stream.PipelineErrorPolicyDrop,
+	}, nil)
+
+	assert.Equal(t, 1, output.Size())
+	assert.Equal(t, messages, input.Acks())
+	assert.Empty(t, input.Nacks())
+}
+
+func TestPipeline_ErrorPolicyRetry(t *testing.T) {
+	callback := &failingCallback{failures: 2}
+	input, output, messages := runFailingPipeline(t, callback, stream.PipelineStageSettings{
+		ErrorPolicy: stream.PipelineErrorPolicyRetry,
+		MaxAttempts: 3,
+	}, nil)
+
+	assert.Equal(t, 3, callback.attempts)
+	assert.Equal(t, 2, output.Size())
+	assert.True(t, output.ContainsBody("poison"))
+	assert.Equal(t, messages, input.Acks())
+	assert.Empty(t, input.Nacks())
+}
+
+func TestPipeline_ErrorPolicyRetryExhausted(t *testing.T) {
+	callback := &failingCallback{failures: 5}
+	deadLetter := stream.NewOutputMemory()
+
+	input, output, messages := runFailingPipeline(t, callback, stream.PipelineStageSettings{
+		ErrorPolicy: stream.PipelineErrorPolicyRetry,
+		MaxAttempts: 2,
+	}, deadLetter)
+
+	assert.Equal(t, 2, callback.attempts)
+	assert.Equal(t, 1, output.Size())
+	assert.Equal(t, 1, deadLetter.Size())
+	assert.True(t, deadLetter.ContainsBody("poison"))
+	assert.Equal(t, messages, input.Acks())
+}
+
+func TestPipeline_ErrorPolicyDeadLetter(t *testing.T) {
+	deadLetter := stream.NewOutputMemory()
+
+	input, output, messages := runFailingPipeline(t, &failingCallback{failures: 1}, stream.PipelineStageSettings{
+		ErrorPolicy: stream.PipelineErrorPolicyDeadLetter,
+	}, deadLetter)
+
+	assert.Equal(t, 1, output.Size())
+	assert.Equal(t, 1, deadLetter.Size())
+	assert.True(t, deadLetter.ContainsBody("poison"))
+	assert.Equal(t, messages, input.Acks())
+	assert.Empty(t, input.Nacks())
+}
+
+type derivingCallback struct {
+}
+
+func (c *derivingCallback) Boot(config cfg.Config, logger mon.Logger) error {
+	return nil
+}
+
+func (c *derivingCallback) Process(ctx context.Context, messages []*stream.Message) ([]*stream.Message, error) {
+	processed := make([]*stream.Message, 0, len(messages))
+	errs := stream.NewPipelineMessageErrors()
+
+	for _, msg := range messages {
+		derived := &stream.Message{Body: msg.Body + "-derived"}
+
+		if msg.Body == "poison" {
+			errs.Add(derived, fmt.Errorf("can not process poison"))
+			continue
+		}
+
+		processed = append(processed, derived)
+	}
+
+	return processed, errs.ErrorOrNil()
+}
+
+func TestPipeline_ErrorPolicyNackDerivedMessage(t *testing.T) {
+	input, output, messages := runFailingPipeline(t, &derivingCallback{}, stream.PipelineStageSettings{}, nil)
+
+	assert.Equal(t, 0, output.Size(), "nothing should be written if the whole batch is read again")
+	assert.Empty(t, input.Acks())
+	assert.Equal(t, messages, input.Nacks())
+}
+
+type erroringCallback struct {
+}
+
+func (c *erroringCallback) Boot(config cfg.Config, logger mon.Logger) error {
+	return nil
+}
+
+func (c *erroringCallback) Process(ctx context.Context, messages []*stream.Message) ([]*stream.Message, error) {
+	return nil, fmt.Errorf("stage is broken")
+}
+
+func TestPipeline_StageError(t *testing.T) {
+	input, output, messages := runFailingPipeline(t, &erroringCallback{}, stream.PipelineStageSettings{}, nil)
+
+	assert.Equal(t, 0, output.Size())
+	assert.Empty(t, input.Acks())
+	assert.Equal(t, messages, input.Nacks())
+}
+
+func TestPipeline_UnknownErrorPolicy(t *testing.T) {
+	logger := mocks.NewLoggerMockedAll()
+	metric := mocks.NewMetricWriterMockedAll()
+
+	pipe := stream.NewPipeline(&callback{})
+	err := pipe.BootWithInterfaces(logger, metric, stream.NewInMemoryInput(&stream.InMemorySettings{}), stream.NewOutputMemory(), &stream.PipelineSettings{
+		Interval:  time.Hour,
+		BatchSize: 1,
+		Stages: []stream.PipelineStageSettings{
+			{ErrorPolicy: "ignore"},
+		},
+	})
+
+	assert.Error(t, err)
+}
@@ -32,5 +32,15 @@ func SnsUnmarshaler(data *string) (*Message, error) {
 	msg := Message{}
 	err = msg.UnmarshalFromString(snsMessage.Message)
 
-	return &msg, err
+	if err != nil || snsMessage.MessageId == "" {
+		return &msg, err
+	}
+
+	if msg.Attributes == nil {
+		msg.Attributes = make(map[string]interface{})
+	}
+
+	msg.Attributes[AttributeSnsMessageId] = snsMessage.MessageId
+
+	return &msg, nil
 }
@@ -7,14 +7,21 @@ import (
 	"github.com/applike/gosoline/pkg/mdl"
 	"github.com/applike/gosoline/pkg/mon"
 	"github.com/applike/gosoline/pkg/stream"
+	"time"
 )
 
 type Subscription struct {
-	Input       string            `cfg:"input"`
-	Output      string            `cfg:"output"`
-	Redis       string            `cfg:"redis"`
-	SourceModel SubscriptionModel `cfg:"source"`
-	TargetModel SubscriptionModel `cfg:"target"`
+	Input          string                       `cfg:"input"`
+	Output         string                       `cfg:"output"`
+	Redis          string                       `cfg:"redis"`
+	RunnerCount    int                          `cfg:"runner_count"`
+	ConsumeTimeout int                          `cfg:"consume_timeout"`
+	Deduplication  stream.DeduplicationSettings `cfg:"deduplication"`
+	SourceModel    SubscriptionModel            `cfg:"source"`
+	TargetModel    SubscriptionModel            `cfg:"target"`
+
+	VisibilityHeartbeat stream.SqsVisibilityHeartbeatSettings `cfg:"visibility_heartbeat"`
+	AckBatch            stream.SqsAckBatchSettings            `cfg:"ack_batch"`
 }
 
 type SubscriptionModel struct {
@@ -61,12 +68,15 @@ func SubscriberFactory(config cfg.Config, logger mon.Logger, transformerMapType
 		}
 
 		settings := Settings{
-			Type:          s.Output,
-			SourceModelId: sourceModelId,
-			TargetModelId: targetModelId,
+			Type:           s.Output,
+			SourceModelId:  sourceModelId,
+			TargetModelId:  targetModelId,
+			RunnerCount:    s.RunnerCount,
+			ConsumeTimeout: time.Duration(s.ConsumeTimeout) * time.Second,
+			Deduplication:  s.Deduplication,
 		}
 
-		input, err := getInputByType(config, logger, s.Input, sourceModelId)
+		input, err := getInputByType(config, logger, s, sourceModelId)
 		if err != nil {
 			logger.Error(err, "could not build subscribers")
 			return modules, err
@@ -96,12 +106,14 @@ func SubscriberFactory(config cfg.Config, logger mon.Logger, transformerMapType
 	return modules, nil
 }
 
-func getInputByType(config cfg.Config, logger mon.Logger, inType string, mId mdl.ModelId) (stream.Input, error) {
-	switch inType {
+func getInputByType(config cfg.Config, logger mon.Logger, s Subscription, mId mdl.ModelId) (stream.Input, error) {
+	switch s.Input {
 	case "sns":
 		inputSettings := stream.SnsInputSettings{
-			QueueId:  mId.Name,
-			WaitTime: 5,
+			QueueId:             mId.Name,
+			WaitTime:            5,
+			VisibilityHeartbeat: s.VisibilityHeartbeat,
+			AckBatch:            s.AckBatch,
 		}
 		inputTargets := []stream.SnsInputTarget{
 			{
@@ -118,7 +130,7 @@ func getInputByType(config cfg.Config, logger mon.Logger, inType string, mId mdl
 		return stream.NewSnsInput(config, logger, inputSettings, inputTargets), nil
 	}
 
-	return nil, fmt.Errorf("there is no input defined of type %s", inType)
+	return nil, fmt.Errorf("there is no input defined of type %s", s.Input)
 }
 
 func getOutputByType(outType string) (Output, error) {
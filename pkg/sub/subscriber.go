@@ -14,10 +14,14 @@ import (
 )
 
 const (
-	MetricNameSuccess = "ModelEventConsumeSuccess"
-	MetricNameFailure = "ModelEventConsumeFailure"
+	MetricNameSuccess   = "ModelEventConsumeSuccess"
+	MetricNameFailure   = "ModelEventConsumeFailure"
+	MetricNameTimeout   = "ModelEventConsumeTimeout"
+	MetricNameDuplicate = "ModelEventConsumeDuplicate"
 )
 
+const DefaultRunnerCount = 10
+
 type Output interface {
 	Boot(config cfg.Config, logger mon.Logger, settings Settings) error
 	Persist(ctx context.Context, model Model, op string) error
@@ -27,6 +31,10 @@ type Settings struct {
 	Type          string
 	SourceModelId mdl.ModelId
 	TargetModelId mdl.ModelId
+	RunnerCount   int
+	// ConsumeTimeout cancels the context for persisting a single message, 0 disables the timeout
+	ConsumeTimeout time.Duration
+	Deduplication  stream.DeduplicationSettings
 }
 
 type Subscriber interface {
@@ -62,8 +70,9 @@ type subscriber struct {
 	modelId  mdl.ModelId
 	name     string
 
-	input  stream.Input
-	output Output
+	input        stream.Input
+	output       Output
+	deduplicator *stream.Deduplicator
 
 	factories TransformerMapVersionFactories
 	transform ModelMsgTransformer
@@ -98,13 +107,29 @@ func (s *subscriber) Boot(config cfg.Config, logger mon.Logger) error {
 	}
 	s.transform = BuildTransformer(versionedTransformers)
 
+	if s.settings.Deduplication.Enabled {
+		dedupSettings := s.settings.Deduplication
+
+		if dedupSettings.Name == "" {
+			dedupSettings.Name = fmt.Sprintf("%s-%s", outType, mId.String())
+		}
+
+		s.deduplicator = stream.NewDeduplicator(config, logger, dedupSettings)
+	}
+
 	return nil
 }
 
 func (s *subscriber) Run(ctx context.Context) error {
 	defer s.logger.Infof("leaving subscriber %s", s.name)
 
-	for i := 0; i < 10; i++ {
+	runnerCount := s.settings.RunnerCount
+
+	if runnerCount <= 0 {
+		runnerCount = DefaultRunnerCount
+	}
+
+	for i := 0; i < runnerCount; i++ {
 		s.cfn.Gof(s.consume, "panic during consuming the subscription")
 	}
 
@@ -114,15 +139,22 @@ func (s *subscriber) Run(ctx context.Context) error {
 		select {
 		case <-ctx.Done():
 			s.input.Stop()
-			return s.cfn.Wait()
+			return s.stop()
 
 		case <-s.cfn.Dying():
 			s.input.Stop()
-			return s.cfn.Err()
+			return s.stop()
 		}
 	}
 }
 
+func (s *subscriber) stop() error {
+	err := s.cfn.Wait()
+	s.FlushAcknowledgements(context.Background())
+
+	return err
+}
+
 func (s *subscriber) consume() error {
 	for {
 		msg, ok := <-s.input.Data()
@@ -141,11 +173,66 @@ func (s *subscriber) handleMessage(msg *stream.Message) {
 	defer s.recover(ctx, msg)
 	defer trans.Finish()
 
+	if s.settings.ConsumeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.settings.ConsumeTimeout)
+		defer cancel()
+	}
+
+	if s.isDuplicate(ctx, msg) {
+		s.writeMetricWithName(MetricNameDuplicate)
+		s.Acknowledge(ctx, msg)
+
+		return
+	}
+
 	err := s.persist(ctx, msg)
+
+	if ctx.Err() == context.DeadlineExceeded {
+		s.logger.WithContext(ctx).Warnf("persisting the message did not finish within %v, the message stays unacknowledged", s.settings.ConsumeTimeout)
+		s.writeMetricWithName(MetricNameTimeout)
+		s.Nack(ctx, msg, stream.NackDefaultDelay)
+
+		return
+	}
+
 	s.writeMetric(err)
 
-	if err == nil {
-		s.Acknowledge(ctx, msg)
+	if err != nil {
+		s.Nack(ctx, msg, stream.NackDefaultDelay)
+		return
+	}
+
+	s.Acknowledge(ctx, msg)
+	s.markProcessed(ctx, msg)
+}
+
+func (s *subscriber) isDuplicate(ctx context.Context, msg *stream.Message) bool {
+	if s.deduplicator == nil {
+		return false
+	}
+
+	duplicate, err := s.deduplicator.IsDuplicate(ctx, msg)
+
+	if err != nil {
+		s.logger.WithContext(ctx).Error(err, "could not check if the message is a duplicate")
+		return false
+	}
+
+	if duplicate {
+		s.logger.WithContext(ctx).Infof("skipping the duplicate message %s", s.deduplicator.GetMessageId(msg))
+	}
+
+	return duplicate
+}
+
+func (s *subscriber) markProcessed(ctx context.Context, msg *stream.Message) {
+	if s.deduplicator == nil {
+		return
+	}
+
+	if err := s.deduplicator.MarkProcessed(ctx, msg); err != nil {
+		s.logger.WithContext(ctx).Error(err, "could not remember the message as processed")
 	}
 }
 
@@ -201,6 +288,10 @@ func (s *subscriber) writeMetric(err error) {
 		metricName = MetricNameFailure
 	}
 
+	s.writeMetricWithName(metricName)
+}
+
+func (s *subscriber) writeMetricWithName(metricName string) {
 	s.metric.WriteOne(&mon.MetricDatum{
 		Priority:   mon.PriorityHigh,
 		Timestamp:  time.Now(),
@@ -236,5 +327,25 @@ func (s *subscriber) getDefaultMetrics() []*mon.MetricDatum {
 			Unit:  mon.UnitCount,
 			Value: 0.0,
 		},
+		{
+			Priority:   mon.PriorityHigh,
+			MetricName: MetricNameTimeout,
+			Dimensions: map[string]string{
+				"Application": s.appId.Application,
+				"ModelId":     s.modelId.String(),
+			},
+			Unit:  mon.UnitCount,
+			Value: 0.0,
+		},
+		{
+			Priority:   mon.PriorityHigh,
+			MetricName: MetricNameDuplicate,
+			Dimensions: map[string]string{
+				"Application": s.appId.Application,
+				"ModelId":     s.modelId.String(),
+			},
+			Unit:  mon.UnitCount,
+			Value: 0.0,
+		},
 	}
 }
@@ -2,7 +2,6 @@ package sub
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"github.com/applike/gosoline/pkg/cfg"
 	"github.com/applike/gosoline/pkg/mon"
@@ -34,14 +33,18 @@ type TransformerMapTypeVersionFactories map[string]TransformerMapVersionFactorie
 
 type TransformerFactory func(config cfg.Config, logger mon.Logger) ModelTransformer
 
+// BuildTransformer transforms the messages by the transformer of their version. Messages of a version without
+// transformer are upcasted to the latest version having one by the upcasters registered for the modelId.
 func BuildTransformer(modelTransformer TransformerMapVersion) ModelMsgTransformer {
 	return func(ctx context.Context, msg *stream.ModelMsg) (Model, error) {
 		if _, ok := modelTransformer[msg.Version]; !ok {
-			return nil, fmt.Errorf("there is no transformer for modelId %s and version %d", msg.ModelId, msg.Version)
+			if err := upcast(ctx, modelTransformer, msg); err != nil {
+				return nil, errors.Wrapf(err, "there is no transformer for modelId %s and version %d", msg.ModelId, msg.Version)
+			}
 		}
 
 		input := modelTransformer[msg.Version].GetInput()
-		err := json.Unmarshal([]byte(msg.Body), input)
+		err := msg.DecodeBody(input)
 
 		if err != nil {
 			return nil, errors.Wrapf(err, "can not unmarshal body for modelId %s and version %d", msg.ModelId, msg.Version)
@@ -56,3 +59,28 @@ func BuildTransformer(modelTransformer TransformerMapVersion) ModelMsgTransforme
 		return model, nil
 	}
 }
+
+func upcast(ctx context.Context, modelTransformer TransformerMapVersion, msg *stream.ModelMsg) error {
+	latest, found := 0, false
+
+	for version := range modelTransformer {
+		if !found || version > latest {
+			latest, found = version, true
+		}
+	}
+
+	if !found || latest < msg.Version {
+		return fmt.Errorf("there is no newer version to upcast to")
+	}
+
+	body, err := stream.UpcastBody(ctx, msg.ModelId, msg.Body, msg.Encoding, msg.Compression, msg.Version, latest)
+
+	if err != nil {
+		return err
+	}
+
+	msg.Body = body
+	msg.Version = latest
+
+	return nil
+}